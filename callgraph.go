@@ -0,0 +1,248 @@
+package germ
+
+import (
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/network"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// callableKinds are the definition sub kinds that can call and be called.
+var callableKinds = map[string]struct{}{
+	"function":    {},
+	"method":      {},
+	"constructor": {},
+}
+
+// SymbolKey identifies a definition: the file defining it and its qualified
+// name, see Tag.QualifiedName.
+type SymbolKey struct {
+	FileName string
+	Symbol   string
+}
+
+// CallGraph is the graph of calls between the function and method
+// definitions of a set of files.
+type CallGraph struct {
+	// Definitions are the definition tags of each callable symbol
+	Definitions map[SymbolKey][]Tag
+	// callees and callers count the calls between symbols
+	callees map[SymbolKey]map[SymbolKey]int
+	callers map[SymbolKey]map[SymbolKey]int
+}
+
+// GetCallGraph extracts the call graph of the given files.
+func (r *RepoMap) GetCallGraph(fnames []string) *CallGraph {
+	return r.buildCallGraph(r.getTagsFromFiles(fnames, commonWords))
+}
+
+// buildCallGraph links each call reference to the callable definitions it
+// resolves to, from the innermost callable definition enclosing it.
+func (r *RepoMap) buildCallGraph(allTags []Tag) *CallGraph {
+	cg := &CallGraph{
+		Definitions: make(map[SymbolKey][]Tag),
+		callees:     make(map[SymbolKey]map[SymbolKey]int),
+		callers:     make(map[SymbolKey]map[SymbolKey]int),
+	}
+
+	defines, qualifiedByName := r.indexDefinitions(allTags)
+
+	// Callable definitions per file, to find the one enclosing each call
+	byFile := make(map[string][]Tag)
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		if _, ok := callableKinds[t.SubKind]; !ok {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
+		k := SymbolKey{FileName: rel, Symbol: t.QualifiedName()}
+		cg.Definitions[k] = append(cg.Definitions[k], t)
+		byFile[rel] = append(byFile[rel], t)
+	}
+
+	for _, t := range allTags {
+		if t.Kind != TagKindRef || t.SubKind != "call" {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
+
+		caller, ok := enclosingDefinition(byFile[rel], t.Line)
+		if !ok {
+			continue
+		}
+		from := SymbolKey{FileName: rel, Symbol: caller.QualifiedName()}
+
+		symbols := resolveReference(t, qualifiedByName[t.Name])
+		for _, symbol := range narrowByImports(r.deps, rel, symbols, defines) {
+			for _, defFile := range r.deps.Targets(rel, defines[symbol]) {
+				to := SymbolKey{FileName: defFile, Symbol: symbol}
+				if _, ok := cg.Definitions[to]; !ok {
+					continue
+				}
+				cg.addCall(from, to)
+			}
+		}
+	}
+
+	return cg
+}
+
+// enclosingDefinition returns the innermost definition whose lines include
+// line, if any.
+func enclosingDefinition(defs []Tag, line int) (Tag, bool) {
+	var best Tag
+	found := false
+	for _, d := range defs {
+		if line < d.Line || line > d.EndLine {
+			continue
+		}
+		if !found || d.EndLine-d.Line < best.EndLine-best.Line {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
+
+// addCall records a call from one symbol to another.
+func (cg *CallGraph) addCall(from, to SymbolKey) {
+	if cg.callees[from] == nil {
+		cg.callees[from] = make(map[SymbolKey]int)
+	}
+	cg.callees[from][to]++
+	if cg.callers[to] == nil {
+		cg.callers[to] = make(map[SymbolKey]int)
+	}
+	cg.callers[to][from]++
+}
+
+// Symbols returns the callable symbols of the graph, sorted.
+func (cg *CallGraph) Symbols() []SymbolKey {
+	keys := make([]SymbolKey, 0, len(cg.Definitions))
+	for k := range cg.Definitions {
+		keys = append(keys, k)
+	}
+	sortSymbolKeys(keys)
+	return keys
+}
+
+// Find returns the symbols whose qualified name is name or ends with .name,
+// eg. Close matches srv.Server.Close.
+func (cg *CallGraph) Find(name string) []SymbolKey {
+	var keys []SymbolKey
+	for k := range cg.Definitions {
+		if k.Symbol == name || strings.HasSuffix(k.Symbol, "."+name) {
+			keys = append(keys, k)
+		}
+	}
+	sortSymbolKeys(keys)
+	return keys
+}
+
+// Callees returns the symbols called by k, sorted.
+func (cg *CallGraph) Callees(k SymbolKey) []SymbolKey {
+	return sortedKeys(cg.callees[k])
+}
+
+// Callers returns the symbols calling k, sorted.
+func (cg *CallGraph) Callers(k SymbolKey) []SymbolKey {
+	return sortedKeys(cg.callers[k])
+}
+
+// Calls returns the number of calls from one symbol to another.
+func (cg *CallGraph) Calls(from, to SymbolKey) int {
+	return cg.callees[from][to]
+}
+
+// TransitiveCallees returns the symbols reachable from k through calls, up to
+// maxDepth calls away, or without limit when maxDepth is zero or less.
+func (cg *CallGraph) TransitiveCallees(k SymbolKey, maxDepth int) []SymbolKey {
+	return cg.reachable(k, maxDepth, cg.callees)
+}
+
+// TransitiveCallers returns the symbols reaching k through calls, up to
+// maxDepth calls away, or without limit when maxDepth is zero or less.
+func (cg *CallGraph) TransitiveCallers(k SymbolKey, maxDepth int) []SymbolKey {
+	return cg.reachable(k, maxDepth, cg.callers)
+}
+
+// reachable walks the adjacency breadth first from k, excluding k itself.
+func (cg *CallGraph) reachable(k SymbolKey, maxDepth int, adj map[SymbolKey]map[SymbolKey]int) []SymbolKey {
+	seen := map[SymbolKey]struct{}{k: {}}
+	frontier := []SymbolKey{k}
+	var keys []SymbolKey
+
+	for depth := 0; len(frontier) > 0 && (maxDepth <= 0 || depth < maxDepth); depth++ {
+		var next []SymbolKey
+		for _, f := range frontier {
+			for n := range adj[f] {
+				if _, ok := seen[n]; ok {
+					continue
+				}
+				seen[n] = struct{}{}
+				keys = append(keys, n)
+				next = append(next, n)
+			}
+		}
+		frontier = next
+	}
+
+	sortSymbolKeys(keys)
+	return keys
+}
+
+// PageRank ranks the symbols of the call graph, with each call weighted by
+// its count, so the most called symbols rank highest.
+func (cg *CallGraph) PageRank(damping, tolerance float64) map[SymbolKey]float64 {
+	g := simple.NewWeightedDirectedGraph(0, 0)
+
+	nodes := make(map[SymbolKey]graph.Node)
+	keyByID := make(map[int64]SymbolKey)
+	for _, k := range cg.Symbols() {
+		n := g.NewNode()
+		g.AddNode(n)
+		nodes[k] = n
+		keyByID[n.ID()] = k
+	}
+
+	for from, callees := range cg.callees {
+		for to, count := range callees {
+			if from == to {
+				continue
+			}
+			g.SetWeightedEdge(g.NewWeightedEdge(nodes[from], nodes[to], float64(count)))
+		}
+	}
+
+	ranks := make(map[SymbolKey]float64, len(nodes))
+	if len(nodes) == 0 {
+		return ranks
+	}
+	for id, rank := range network.PageRank(g, damping, tolerance) {
+		ranks[keyByID[id]] = rank
+	}
+	return ranks
+}
+
+// sortedKeys returns the keys of a symbol set, sorted.
+func sortedKeys(m map[SymbolKey]int) []SymbolKey {
+	keys := make([]SymbolKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortSymbolKeys(keys)
+	return keys
+}
+
+// sortSymbolKeys sorts symbols by file, then qualified name.
+func sortSymbolKeys(keys []SymbolKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].FileName != keys[j].FileName {
+			return keys[i].FileName < keys[j].FileName
+		}
+		return keys[i].Symbol < keys[j].Symbol
+	})
+}
@@ -0,0 +1,75 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCallGraph verifies calls are attributed to their enclosing definition
+// and resolved to callable definitions, and the graph can be walked.
+func TestCallGraph(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go": `package main
+
+func main() {
+	srv.Serve()
+	cleanup()
+}
+
+func cleanup() {
+	logLine()
+}
+`,
+		"srv/srv.go": `package srv
+
+func Serve() {
+	dispatch()
+	dispatch()
+}
+
+func dispatch() {
+	logLine()
+}
+
+func logLine() {}
+`,
+		"util.py": `def parse_config(text):
+    return split_tokens(text)
+
+
+def split_tokens(text):
+    return text.split()
+`,
+	})
+
+	r := &RepoMap{root: root}
+	cg := r.GetCallGraph(fnames)
+
+	mainFn := SymbolKey{FileName: "main.go", Symbol: "main.main"}
+	cleanup := SymbolKey{FileName: "main.go", Symbol: "main.cleanup"}
+	serve := SymbolKey{FileName: "srv/srv.go", Symbol: "srv.Serve"}
+	dispatch := SymbolKey{FileName: "srv/srv.go", Symbol: "srv.dispatch"}
+	logLine := SymbolKey{FileName: "srv/srv.go", Symbol: "srv.logLine"}
+
+	assert.Equal(t, []SymbolKey{cleanup, serve}, cg.Callees(mainFn))
+	assert.Equal(t, []SymbolKey{cleanup, dispatch}, cg.Callers(logLine))
+	assert.Equal(t, 2, cg.Calls(serve, dispatch))
+	assert.Equal(t, []SymbolKey{serve}, cg.Find("Serve"))
+
+	assert.Equal(t, []SymbolKey{cleanup, serve, dispatch, logLine}, cg.TransitiveCallees(mainFn, 0))
+	assert.Equal(t, []SymbolKey{cleanup, serve}, cg.TransitiveCallees(mainFn, 1))
+	assert.Equal(t, []SymbolKey{cleanup, mainFn, serve, dispatch}, cg.TransitiveCallers(logLine, 0))
+
+	parse := SymbolKey{FileName: "util.py", Symbol: "parse_config"}
+	split_tokens := SymbolKey{FileName: "util.py", Symbol: "split_tokens"}
+	assert.Equal(t, []SymbolKey{split_tokens}, cg.Callees(parse))
+
+	t.Run("PageRank", func(t *testing.T) {
+		ranks := cg.PageRank(0.85, 1e-6)
+		assert.Len(t, ranks, len(cg.Symbols()))
+		assert.Greater(t, ranks[logLine], ranks[mainFn])
+		assert.Greater(t, ranks[dispatch], ranks[mainFn])
+	})
+}
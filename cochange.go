@@ -0,0 +1,188 @@
+package germ
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultCoChangeWindow is the git history mined for co-changes
+	defaultCoChangeWindow = 365 * 24 * time.Hour
+	// maxCoChangeFiles skips commits touching more files, eg. mass renames
+	// or formatting, whose pairs say little about coupling
+	maxCoChangeFiles = 50
+)
+
+// CoChanges counts the commits touching each file and each pair of files of
+// the git history.
+type CoChanges struct {
+	// commits maps files to the number of commits touching them
+	commits map[string]int
+	// pairs maps files to the files committed with them and the number of
+	// commits they share
+	pairs map[string]map[string]int
+}
+
+// RelatedFile is a file committed together with another.
+type RelatedFile struct {
+	FileName string
+	// Support is the number of commits touching both files
+	Support int
+	// Confidence is the share of the commits touching the queried file that
+	// also touch this one
+	Confidence float64
+}
+
+// GetCoChanges mines the git history of the files under root over the given
+// window, ending now, for files committed together. Paths are relative to
+// root.
+func GetCoChanges(root string, window time.Duration) (*CoChanges, error) {
+	h, err := GetGitHistory(root, window)
+	if err != nil {
+		return nil, err
+	}
+	return h.CoChanges, nil
+}
+
+func newCoChanges() *CoChanges {
+	return &CoChanges{
+		commits: make(map[string]int),
+		pairs:   make(map[string]map[string]int),
+	}
+}
+
+// addCommit counts a commit touching the given distinct files, unless it
+// touches more than maxCoChangeFiles.
+func (cc *CoChanges) addCommit(files []string) {
+	if len(files) > maxCoChangeFiles {
+		return
+	}
+	for _, a := range files {
+		cc.commits[a]++
+		for _, b := range files {
+			if a == b {
+				continue
+			}
+			if cc.pairs[a] == nil {
+				cc.pairs[a] = make(map[string]int)
+			}
+			cc.pairs[a][b]++
+		}
+	}
+}
+
+// Support returns the number of commits touching both files.
+func (cc *CoChanges) Support(a, b string) int {
+	return cc.pairs[a][b]
+}
+
+// Confidence returns the share of the commits touching a that also touch b.
+func (cc *CoChanges) Confidence(a, b string) float64 {
+	if cc.commits[a] == 0 {
+		return 0
+	}
+	return float64(cc.pairs[a][b]) / float64(cc.commits[a])
+}
+
+// Related returns the files committed with rel at least minSupport times and
+// with at least minConfidence, by decreasing confidence then support.
+func (cc *CoChanges) Related(rel string, minSupport int, minConfidence float64) []RelatedFile {
+	var related []RelatedFile
+	for other, support := range cc.pairs[rel] {
+		confidence := cc.Confidence(rel, other)
+		if support < minSupport || confidence < minConfidence {
+			continue
+		}
+		related = append(related, RelatedFile{FileName: other, Support: support, Confidence: confidence})
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Confidence != related[j].Confidence {
+			return related[i].Confidence > related[j].Confidence
+		}
+		if related[i].Support != related[j].Support {
+			return related[i].Support > related[j].Support
+		}
+		return related[i].FileName < related[j].FileName
+	})
+	return related
+}
+
+// WithCoChangeWeight links files committed together in the git history with
+// an edge of the given weight, scaled by the confidence of the pair, when
+// ranking, so a handler pulls in its migration even when they share no
+// identifier. See the CoChange thresholds of RankingConfig. Zero disables it.
+func WithCoChangeWeight(value float64) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.coChangeWeight = value
+	}
+}
+
+// RelatedFiles returns the files most often committed together with fname,
+// above the co-change thresholds of the ranking config.
+func (r *RepoMap) RelatedFiles(fname string) ([]RelatedFile, error) {
+	cc, err := GetCoChanges(r.root, r.coChangeWindow())
+	if err != nil {
+		return nil, err
+	}
+	cfg := r.ranking()
+	return cc.Related(r.GetRelFname(fname), cfg.CoChangeMinSupport, cfg.CoChangeMinConfidence), nil
+}
+
+// coChangeWindow is the git history window of the git history signals, if
+// set, or defaultCoChangeWindow.
+func (r *RepoMap) coChangeWindow() time.Duration {
+	if r.historyWindow > 0 {
+		return r.historyWindow
+	}
+	return defaultCoChangeWindow
+}
+
+// coChanges returns the co-changes of the git history loaded for the git
+// history signals, if enabled, so git log runs once per map, or mines them.
+func (r *RepoMap) coChanges() (*CoChanges, error) {
+	if r.historyWindow <= 0 {
+		return GetCoChanges(r.root, r.coChangeWindow())
+	}
+	if r.history == nil {
+		return nil, errors.New("git history unavailable")
+	}
+	return r.history.CoChanges, nil
+}
+
+// getCoChangeEdges returns an edge between each pair of the given files
+// committed together above the co-change thresholds, in both directions,
+// weighted by the RepoMap co-change weight times the confidence of the
+// direction.
+func (r *RepoMap) getCoChangeEdges(allFnames []string) []fileEdge {
+	cc, err := r.coChanges()
+	if err != nil {
+		log.Warn().Err(err).Msg("co-change edges disabled")
+		return nil
+	}
+
+	files := make(map[string]struct{}, len(allFnames))
+	for _, f := range allFnames {
+		files[r.GetRelFname(f)] = struct{}{}
+	}
+
+	cfg := r.ranking()
+	var edges []fileEdge
+	for src := range files {
+		for _, rf := range cc.Related(src, cfg.CoChangeMinSupport, cfg.CoChangeMinConfidence) {
+			if _, ok := files[rf.FileName]; !ok {
+				continue
+			}
+			edges = append(edges, fileEdge{src: src, dst: rf.FileName, weight: r.coChangeWeight * rf.Confidence, kind: "cochange"})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].src != edges[j].src {
+			return edges[i].src < edges[j].src
+		}
+		return edges[i].dst < edges[j].dst
+	})
+	return edges
+}
@@ -0,0 +1,82 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseCoChanges verifies support and confidence of files committed
+// together.
+func TestParseCoChanges(t *testing.T) {
+	out := "\x001700000300\tme@example.com\x00\napi/handler.go\x00db/001_users.sql\x00" +
+		"\x001700000200\tme@example.com\x00\napi/handler.go\x00db/001_users.sql\x00" +
+		"\x001700000100\tme@example.com\x00\napi/handler.go\x00README.md\x00" +
+		"\x001700000000\tme@example.com\x00\napi/handler.go\x00"
+
+	cc := parseGitLog([]byte(out), "me@example.com").CoChanges
+
+	assert.Equal(t, 2, cc.Support("api/handler.go", "db/001_users.sql"))
+	assert.Equal(t, 0.5, cc.Confidence("api/handler.go", "db/001_users.sql"))
+	assert.Equal(t, 1.0, cc.Confidence("db/001_users.sql", "api/handler.go"))
+	assert.Equal(t, 0.0, cc.Confidence("missing.go", "api/handler.go"))
+
+	assert.Equal(t, []RelatedFile{
+		{FileName: "db/001_users.sql", Support: 2, Confidence: 0.5},
+	}, cc.Related("api/handler.go", 2, 0.5))
+	assert.Equal(t, []RelatedFile{
+		{FileName: "db/001_users.sql", Support: 2, Confidence: 0.5},
+		{FileName: "README.md", Support: 1, Confidence: 0.25},
+	}, cc.Related("api/handler.go", 1, 0))
+}
+
+// TestRelatedFiles verifies co-changes are mined from git and linked as file
+// edges between the ranked files.
+func TestRelatedFiles(t *testing.T) {
+	repo := gitInit(t)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		gitCommit(t, repo, "me@example.com", now.Add(-time.Duration(3-i)*time.Hour), map[string]string{
+			"api/handler.go":   "package api\n\n// revision " + string(rune('a'+i)) + "\n",
+			"db/001_users.sql": "-- revision " + string(rune('a'+i)) + "\n",
+		})
+	}
+	gitCommit(t, repo, "me@example.com", now.Add(-time.Hour), map[string]string{"api/other.go": "package api\n"})
+
+	r := NewRepoMap(repo, nil, WithCoChangeWeight(2))
+	related, err := r.RelatedFiles(filepath.Join(repo, "api/handler.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, []RelatedFile{{FileName: "db/001_users.sql", Support: 3, Confidence: 1}}, related)
+
+	fnames := []string{
+		filepath.Join(repo, "api/handler.go"),
+		filepath.Join(repo, "api/other.go"),
+		filepath.Join(repo, "db/001_users.sql"),
+	}
+	assert.Equal(t, []fileEdge{
+		{src: "api/handler.go", dst: "db/001_users.sql", weight: 2, kind: "cochange"},
+		{src: "db/001_users.sql", dst: "api/handler.go", weight: 2, kind: "cochange"},
+	}, r.getFileEdges(fnames, nil))
+
+	// With the git history signals, the co-changes come from the history
+	// already loaded rather than another git log
+	root := t.TempDir()
+	r = NewRepoMap(root, nil, WithCoChangeWeight(2), WithGitHistory(time.Hour))
+	r.history = &GitHistory{CoChanges: newCoChanges()}
+	for i := 0; i < 2; i++ {
+		r.history.CoChanges.addCommit([]string{"api/handler.go", "db/001_users.sql"})
+	}
+	fnames = []string{filepath.Join(root, "api/handler.go"), filepath.Join(root, "db/001_users.sql")}
+	assert.Len(t, r.getFileEdges(fnames, nil), 2)
+	r.history = nil
+	assert.Empty(t, r.getFileEdges(fnames, nil))
+
+	// Outside a git repository there are no co-changes
+	r = NewRepoMap(t.TempDir(), nil, WithCoChangeWeight(2))
+	_, err = r.RelatedFiles("handler.go")
+	assert.Error(t, err)
+	assert.Empty(t, r.getFileEdges(nil, nil))
+}
@@ -0,0 +1,242 @@
+package germ
+
+import (
+	"bufio"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffNeighborWeight is the mention weight of the callers and callees of the
+// symbols changed by a diff, and of the files referencing them
+const diffNeighborWeight = 0.5
+
+// hunkPattern matches the header of a unified diff hunk
+var hunkPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// FileDiff is the change of a file in a unified diff.
+type FileDiff struct {
+	// OldPath and NewPath are the paths before and after the change, without
+	// their a/ and b/ prefixes, "" when the file is created or deleted
+	OldPath string
+	NewPath string
+	// Lines are the 1-based lines of the new file added or changed, or
+	// following removed lines
+	Lines []int
+}
+
+// ParseDiff parses the files and changed lines of a unified diff, as written
+// by git diff or diff -u.
+func ParseDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var cur *FileDiff
+	// newSeen is set once the +++ line of the current file is read
+	var newSeen bool
+	// The lines left in the current hunk, and the current new file line
+	var oldLeft, newLeft, line int
+
+	atoi := func(s string, def int) int {
+		if s == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(text, "+"):
+				cur.Lines = appendUniqueInt(cur.Lines, line)
+				line++
+				newLeft--
+			case strings.HasPrefix(text, "-"):
+				// Removed lines are shown by the line taking their place
+				cur.Lines = appendUniqueInt(cur.Lines, max(line, 1))
+				oldLeft--
+			case strings.HasPrefix(text, `\`):
+				// \ No newline at end of file
+			default:
+				line++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "diff "):
+			files = append(files, FileDiff{})
+			cur, newSeen = &files[len(files)-1], false
+		case strings.HasPrefix(text, "--- "):
+			if cur == nil || newSeen {
+				// diff -u output has no diff header
+				files = append(files, FileDiff{})
+				cur, newSeen = &files[len(files)-1], false
+			}
+			cur.OldPath = diffPath(text[4:], "a/")
+		case strings.HasPrefix(text, "+++ ") && cur != nil:
+			cur.NewPath, newSeen = diffPath(text[4:], "b/"), true
+		case cur != nil:
+			if m := hunkPattern.FindStringSubmatch(text); m != nil {
+				oldLeft, newLeft, line = atoi(m[2], 1), atoi(m[4], 1), atoi(m[3], 1)
+				if newLeft == 0 {
+					// A removal only hunk starts after the line it is at
+					line++
+				}
+			}
+		}
+	}
+	return files
+}
+
+// diffPath returns the path of a ---/+++ line of a diff, without its prefix,
+// or "" for /dev/null.
+func diffPath(p, prefix string) string {
+	if i := strings.IndexByte(p, '\t'); i >= 0 {
+		// diff -u appends the modification time
+		p = p[:i]
+	}
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// GetGitDiff returns the git diff of the files under root, with paths
+// relative to root, between the given revisions, eg. main...HEAD, or of the
+// working tree against HEAD when none are given.
+func GetGitDiff(root string, revisions ...string) (string, error) {
+	if len(revisions) == 0 {
+		revisions = []string{"HEAD"}
+	}
+	args := append([]string{"diff", "--relative", "--no-color", "--no-ext-diff", "-U0"}, revisions...)
+	out, err := runGit(root, append(args, "--")...)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// GetDiffMentions returns the changes of diff to the files among fnames as
+// mentions: the changed files and lines, the symbols whose definition
+// includes a changed line, and with diffNeighborWeight their callers and
+// callees and the files referencing them. Paths match the files the way
+// stack trace paths do, see GetFrames, so diff -u output of two directories
+// and diffs taken from a parent directory resolve.
+func (r *RepoMap) GetDiffMentions(diff string, fnames []string) Mentions {
+	return r.getDiffMentions(diff, fnames, nil)
+}
+
+// getDiffMentions is GetDiffMentions with the tags of the files, parsed when
+// nil and some file changed.
+func (r *RepoMap) getDiffMentions(diff string, fnames []string, allTags []Tag) Mentions {
+	m := Mentions{
+		Files:  make(map[string]float64),
+		Idents: make(map[string]float64),
+		Lines:  make(map[string][]int),
+	}
+
+	files := make([]string, 0, len(fnames))
+	for _, f := range fnames {
+		files = append(files, filepath.ToSlash(r.GetRelFname(f)))
+	}
+	sort.Strings(files)
+
+	changed := make(map[string][]int)
+	for _, fd := range ParseDiff(diff) {
+		rel, ok := r.resolveFramePath(fd.NewPath, files)
+		if !ok {
+			continue
+		}
+		rel = filepath.FromSlash(rel)
+		m.addFile(rel, mentionExact)
+		for _, line := range fd.Lines {
+			m.Lines[rel] = appendUniqueInt(m.Lines[rel], line-1)
+		}
+		changed[rel] = m.Lines[rel]
+	}
+	if len(changed) == 0 {
+		return m
+	}
+
+	if allTags == nil {
+		allTags = r.getTagsFromFiles(fnames, commonWords)
+	}
+	cg := r.buildCallGraph(allTags)
+
+	symbols := make(map[string]struct{})
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
+		if !spansAny(t, changed[rel]) {
+			continue
+		}
+		m.addIdent(t.Name, mentionExact)
+		symbols[t.Name] = struct{}{}
+
+		k := SymbolKey{FileName: rel, Symbol: t.QualifiedName()}
+		for _, n := range append(cg.Callers(k), cg.Callees(k)...) {
+			m.addFile(n.FileName, diffNeighborWeight)
+			for _, def := range cg.Definitions[n] {
+				m.addIdent(def.Name, diffNeighborWeight)
+			}
+		}
+	}
+
+	// The files using the changed symbols, beyond calls, eg. of a type
+	for _, t := range allTags {
+		if t.Kind != TagKindRef {
+			continue
+		}
+		if _, ok := symbols[t.Name]; ok {
+			m.addFile(r.GetRelFname(t.FilePath), diffNeighborWeight)
+		}
+	}
+	return m
+}
+
+// spansAny reports whether any of the 0-based lines is within the
+// definition t.
+func spansAny(t Tag, lines []int) bool {
+	for _, l := range lines {
+		if l >= t.Line && l <= t.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateForDiff generates the repo map centered on a unified diff, see
+// GetDiffMentions: the changed lines are shown first, and the changed symbols
+// and their neighbors rank highest within the token budget.
+func (r *RepoMap) GenerateForDiff(chatFiles, otherFiles []string, diff string) string {
+	fnames := uniqueElements(chatFiles, otherFiles)
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	m := r.getDiffMentions(diff, fnames, allTags)
+
+	// The map is generated from the tags the mentions were extracted from
+	r.linesOfInterest, r.parsedTags = m.Lines, allTags
+	defer func() { r.linesOfInterest, r.parsedTags = nil, nil }()
+
+	return r.Generate(chatFiles, otherFiles, m.FnameSet(minMentionWeight), m.IdentSet(minMentionWeight))
+}
+
+// GenerateForGitDiff generates the repo map centered on the git diff between
+// the given revisions, or of the working tree, see GetGitDiff and
+// GenerateForDiff.
+func (r *RepoMap) GenerateForGitDiff(chatFiles, otherFiles []string, revisions ...string) (string, error) {
+	diff, err := GetGitDiff(r.root, revisions...)
+	if err != nil {
+		return "", err
+	}
+	return r.GenerateForDiff(chatFiles, otherFiles, diff), nil
+}
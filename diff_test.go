@@ -0,0 +1,159 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseDiff verifies the changed lines of each file of a diff are
+// found, including removals, created and deleted files and diff -u output.
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/srv/server.go b/srv/server.go
+index 3b18e51..a9c2f04 100644
+--- a/srv/server.go
++++ b/srv/server.go
+@@ -3,3 +3,4 @@ import "fmt"
+ func dispatch() {
+-	fmt.Println("old")
++	fmt.Println("new")
++	-- not a header
+ }
+@@ -20,2 +21,0 @@ func drain() {
+-	// gone
+--- still in the hunk
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++text
+\ No newline at end of file
+diff --git a/legacy.go b/legacy.go
+deleted file mode 100644
+--- a/legacy.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package legacy
+--- util.py	2024-05-01 10:00:00
++++ util.py	2024-05-02 10:00:00
+@@ -1 +1 @@
+-x = 1
++x = 2
+`
+
+	assert.Equal(t, []FileDiff{
+		{OldPath: "srv/server.go", NewPath: "srv/server.go", Lines: []int{4, 5, 22}},
+		{NewPath: "docs/new.md", Lines: []int{1, 2}},
+		{OldPath: "legacy.go", Lines: []int{1}},
+		{OldPath: "util.py", NewPath: "util.py", Lines: []int{1}},
+	}, ParseDiff(diff))
+
+	assert.Empty(t, ParseDiff(""))
+}
+
+// TestGetDiffMentions verifies the changed symbols, their callers and
+// callees, and the files using them are mentioned.
+func TestGetDiffMentions(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go": `package main
+
+func main() {
+	srv.Serve()
+}
+`,
+		"srv/srv.go": `package srv
+
+func Serve() {
+	dispatch()
+}
+
+func dispatch() {
+	logLine()
+}
+
+func logLine() {}
+`,
+		"srv/stats.go": `package srv
+
+type Counter struct{}
+
+func unrelatedHelper() {}
+`,
+	})
+
+	r := NewRepoMap(root, nil)
+	m := r.GetDiffMentions(`--- a/srv/srv.go
++++ b/srv/srv.go
+@@ -8 +8,2 @@ func dispatch() {
+-	logLine()
++	logLine()
++	logLine()
+--- a/outside.go
++++ b/outside.go
+@@ -1 +1 @@
+-package x
++package y
+`, fnames)
+
+	assert.Equal(t, map[string]float64{"srv/srv.go": mentionExact}, m.Files)
+	assert.Equal(t, map[string]float64{
+		"dispatch": mentionExact,
+		"Serve":    diffNeighborWeight,
+		"logLine":  diffNeighborWeight,
+	}, m.Idents)
+	assert.Equal(t, map[string][]int{"srv/srv.go": {7, 8}}, m.Lines)
+
+	m = r.GetDiffMentions(`--- a/srv/srv.go
++++ b/srv/srv.go
+@@ -3 +3 @@
+-func Serve() {
++func Serve() {
+`, fnames)
+	assert.Equal(t, map[string]float64{"srv/srv.go": mentionExact, "main.go": diffNeighborWeight}, m.Files)
+	assert.NotContains(t, m.Idents, "unrelatedHelper")
+
+	assert.Empty(t, r.GetDiffMentions("", fnames).Files)
+
+	// Paths match by suffix: diff -u of two trees, or a diff of the parent
+	// directory
+	for _, prefix := range []string{"old/srv/srv.go\n+++ new/", "a/module/srv/srv.go\n+++ b/module/"} {
+		m = r.GetDiffMentions("--- "+prefix+"srv/srv.go\n@@ -8 +8 @@ func dispatch() {\n-\tlogLine()\n+\tlogLine()\n", fnames)
+		assert.Equal(t, map[string]float64{"srv/srv.go": mentionExact}, m.Files)
+		assert.Equal(t, map[string][]int{"srv/srv.go": {7}}, m.Lines)
+	}
+}
+
+// TestGenerateForGitDiff verifies the map of a working tree change shows the
+// changed lines.
+func TestGenerateForGitDiff(t *testing.T) {
+	repo := gitInit(t)
+	gitCommit(t, repo, "me@example.com", time.Now(), map[string]string{
+		"engine.go": "package app\n\nfunc startEngine() {\n\twarmCaches()\n}\n",
+		"caches.go": "package app\n\nfunc warmCaches() {}\n",
+	})
+	fnames := writeTestFiles(t, repo, map[string]string{
+		"engine.go": "package app\n\nfunc startEngine() {\n\twarmCaches()\n\twarmCaches()\n}\n",
+	})
+
+	diff, err := GetGitDiff(repo)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []FileDiff{{OldPath: "engine.go", NewPath: "engine.go", Lines: []int{5}}}, ParseDiff(diff))
+
+	r := NewRepoMap(repo, nil, WithLinesOfInterestMarked(true))
+	out, err := r.GenerateForGitDiff(nil, append(fnames, filepath.Join(repo, "caches.go")))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, out, "engine.go")
+	assert.Contains(t, out, "warmCaches()")
+
+	_, err = GetGitDiff(t.TempDir())
+	assert.Error(t, err)
+}
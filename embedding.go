@@ -0,0 +1,334 @@
+package germ
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultHashDimensions is the vector size of the hashed embedder
+	defaultHashDimensions = 256
+	// maxSnippetLines bounds the lines of a definition embedded
+	maxSnippetLines = 20
+	// embedBatchSize is the number of texts embedded per Embedder call
+	embedBatchSize = 64
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// related the texts are, eg. a local model or a remote embeddings API.
+type Embedder interface {
+	// Name identifies the embedder and its model. Vectors persisted by
+	// another embedder are discarded.
+	Name() string
+	// Embed returns the vector of each text, in order.
+	Embed(texts []string) ([][]float32, error)
+}
+
+// HashEmbedder is a deterministic local Embedder hashing the words of a text,
+// see lexicalTerms, into a bag of words vector. It needs no model, so it
+// works offline and in tests, but only relates texts sharing words.
+type HashEmbedder struct {
+	// Dimensions is the vector size, defaultHashDimensions when zero
+	Dimensions int
+}
+
+// Name implements Embedder.
+func (e HashEmbedder) Name() string {
+	return "hash-" + strconv.Itoa(e.dimensions())
+}
+
+// Embed implements Embedder. Each word adds one, or minus one depending on
+// its hash, to the dimension it hashes to, and vectors are L2 normalized.
+func (e HashEmbedder) Embed(texts []string) ([][]float32, error) {
+	dims := e.dimensions()
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, dims)
+		for _, term := range lexicalTerms(text) {
+			h := fnv.New64a()
+			h.Write([]byte(term))
+			sum := h.Sum64()
+			if sum>>63 == 1 {
+				v[sum%uint64(dims)]--
+			} else {
+				v[sum%uint64(dims)]++
+			}
+		}
+		normalizeVector(v)
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (e HashEmbedder) dimensions() int {
+	if e.Dimensions > 0 {
+		return e.Dimensions
+	}
+	return defaultHashDimensions
+}
+
+// normalizeVector scales v to unit length, unless zero.
+func normalizeVector(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, 0
+// when either is zero or their sizes differ.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// VectorEntry is the vector of a definition, with the hash of the text it
+// was embedded from to detect changes.
+type VectorEntry struct {
+	FileName string    `json:"file"`
+	Symbol   string    `json:"symbol"`
+	Hash     string    `json:"hash"`
+	Vector   []float32 `json:"vector"`
+}
+
+// VectorIndex holds the vectors of the definitions of a repository, embedded
+// by a single Embedder.
+type VectorIndex struct {
+	// Model is the name of the embedder of the vectors
+	Model   string
+	entries map[SymbolKey]VectorEntry
+}
+
+// vectorIndexFile is the JSON layout of a persisted VectorIndex.
+type vectorIndexFile struct {
+	Model   string        `json:"model"`
+	Entries []VectorEntry `json:"entries"`
+}
+
+// NewVectorIndex returns an empty vector index.
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{entries: make(map[SymbolKey]VectorEntry)}
+}
+
+// LoadVectorIndex reads the vector index persisted at path, or returns an
+// empty one when the file does not exist.
+func LoadVectorIndex(path string) (*VectorIndex, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewVectorIndex(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vector index (%s): %w", path, err)
+	}
+
+	var f vectorIndexFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse vector index (%s): %w", path, err)
+	}
+	ix := NewVectorIndex()
+	ix.Model = f.Model
+	for _, e := range f.Entries {
+		ix.entries[SymbolKey{FileName: e.FileName, Symbol: e.Symbol}] = e
+	}
+	return ix, nil
+}
+
+// Save persists the vector index at path, creating its directory.
+func (ix *VectorIndex) Save(path string) error {
+	f := vectorIndexFile{Model: ix.Model, Entries: make([]VectorEntry, 0, len(ix.entries))}
+	for _, e := range ix.entries {
+		f.Entries = append(f.Entries, e)
+	}
+	sort.Slice(f.Entries, func(i, j int) bool {
+		if f.Entries[i].FileName != f.Entries[j].FileName {
+			return f.Entries[i].FileName < f.Entries[j].FileName
+		}
+		return f.Entries[i].Symbol < f.Entries[j].Symbol
+	})
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode vector index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create vector index directory: %w", err)
+	}
+	// Write then rename so readers never see a partial index
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write vector index (%s): %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Len returns the number of vectors of the index.
+func (ix *VectorIndex) Len() int {
+	return len(ix.entries)
+}
+
+// Update embeds the texts of the definitions new or changed since the last
+// update, and drops the vectors of the definitions no longer in texts. All
+// vectors are embedded again when the embedder changed.
+func (ix *VectorIndex) Update(e Embedder, texts map[SymbolKey]string) error {
+	if ix.Model != e.Name() {
+		ix.Model = e.Name()
+		ix.entries = make(map[SymbolKey]VectorEntry)
+	}
+
+	for k := range ix.entries {
+		if _, ok := texts[k]; !ok {
+			delete(ix.entries, k)
+		}
+	}
+
+	var stale []SymbolKey
+	for k, text := range texts {
+		if ix.entries[k].Hash != textHash(text) {
+			stale = append(stale, k)
+		}
+	}
+	sortSymbolKeys(stale)
+
+	for start := 0; start < len(stale); start += embedBatchSize {
+		batch := stale[start:min(start+embedBatchSize, len(stale))]
+		batchTexts := make([]string, len(batch))
+		for i, k := range batch {
+			batchTexts[i] = texts[k]
+		}
+		vectors, err := e.Embed(batchTexts)
+		if err != nil {
+			return fmt.Errorf("failed to embed definitions: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedder %s returned %d vectors for %d texts", e.Name(), len(vectors), len(batch))
+		}
+		for i, k := range batch {
+			ix.entries[k] = VectorEntry{FileName: k.FileName, Symbol: k.Symbol, Hash: textHash(batchTexts[i]), Vector: vectors[i]}
+		}
+	}
+	return nil
+}
+
+// Similarities returns the cosine similarity of each definition to a vector.
+func (ix *VectorIndex) Similarities(v []float32) map[SymbolKey]float64 {
+	sims := make(map[SymbolKey]float64, len(ix.entries))
+	for k, e := range ix.entries {
+		sims[k] = cosineSimilarity(v, e.Vector)
+	}
+	return sims
+}
+
+// textHash returns a short hash of a text.
+func textHash(text string) string {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// WithEmbedder blends the semantic similarity of definitions to the query of
+// GenerateWithQuery into its relevance, see RankingConfig.SemanticWeight.
+// HashEmbedder works offline.
+func WithEmbedder(value Embedder) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.embedder = value
+	}
+}
+
+// WithEmbeddingCache persists the vectors of the definitions at the given
+// path, so only new and changed definitions are embedded again. Without it
+// every query embeds all definitions.
+func WithEmbeddingCache(path string) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.embeddingCache = path
+	}
+}
+
+// definitionSnippets returns the text embedded for each definition: its
+// file, doc comment and first lines.
+func (r *RepoMap) definitionSnippets(allTags []Tag) map[SymbolKey]string {
+	snippets := make(map[SymbolKey]string)
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		lines := r.sourceLines(t.FilePath)
+		rel := r.GetRelFname(t.FilePath)
+
+		parts := []string{filepath.ToSlash(rel), docComment(lines, t.Line)}
+		if t.Line < len(lines) {
+			end := min(t.EndLine, t.Line+maxSnippetLines-1, len(lines)-1)
+			parts = append(parts, strings.Join(lines[t.Line:max(end, t.Line)+1], "\n"))
+		} else {
+			parts = append(parts, t.Name)
+		}
+
+		k := SymbolKey{FileName: rel, Symbol: t.QualifiedName()}
+		if _, ok := snippets[k]; !ok {
+			snippets[k] = strings.Join(parts, "\n")
+		}
+	}
+	return snippets
+}
+
+// semanticScores returns the cosine similarity of the definitions to the
+// query, embedded by the RepoMap embedder, or nil without an embedder or on
+// failure.
+func (r *RepoMap) semanticScores(allTags []Tag) map[SymbolKey]float64 {
+	if r.embedder == nil {
+		return nil
+	}
+
+	ix := NewVectorIndex()
+	if r.embeddingCache != "" {
+		var err error
+		if ix, err = LoadVectorIndex(r.embeddingCache); err != nil {
+			log.Warn().Err(err).Msg("ignoring embedding cache")
+			ix = NewVectorIndex()
+		}
+	}
+
+	if err := ix.Update(r.embedder, r.definitionSnippets(allTags)); err != nil {
+		log.Warn().Err(err).Msg("semantic ranking disabled")
+		return nil
+	}
+	if r.embeddingCache != "" {
+		if err := ix.Save(r.embeddingCache); err != nil {
+			log.Warn().Err(err).Msg("failed to save embedding cache")
+		}
+	}
+
+	vectors, err := r.embedder.Embed([]string{r.query})
+	if err != nil || len(vectors) != 1 {
+		log.Warn().Err(err).Msg("failed to embed query, semantic ranking disabled")
+		return nil
+	}
+	return ix.Similarities(vectors[0])
+}
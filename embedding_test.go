@@ -0,0 +1,122 @@
+package germ
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingEmbedder records the texts it embeds.
+type countingEmbedder struct {
+	HashEmbedder
+	embedded []string
+	err      error
+}
+
+func (e *countingEmbedder) Embed(texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.embedded = append(e.embedded, texts...)
+	return e.HashEmbedder.Embed(texts)
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := HashEmbedder{Dimensions: 64}
+	assert.Equal(t, "hash-64", e.Name())
+	assert.Equal(t, "hash-256", HashEmbedder{}.Name())
+
+	vectors, err := e.Embed([]string{"retry the upload with backoff", "retryUpload backoff", "render the sidebar", ""})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, vectors[0], 64)
+	assert.InDelta(t, 1.0, cosineSimilarity(vectors[0], vectors[0]), 1e-6)
+	assert.Greater(t, cosineSimilarity(vectors[0], vectors[1]), cosineSimilarity(vectors[0], vectors[2]))
+	assert.Equal(t, 0.0, cosineSimilarity(vectors[0], vectors[3]))
+	assert.Equal(t, 0.0, cosineSimilarity(vectors[0], []float32{1}))
+
+	again, _ := e.Embed([]string{"retry the upload with backoff"})
+	assert.Equal(t, vectors[0], again[0])
+}
+
+// TestVectorIndex verifies only new and changed definitions are embedded,
+// and the index persists.
+func TestVectorIndex(t *testing.T) {
+	upload := SymbolKey{FileName: "upload.go", Symbol: "retryUpload"}
+	render := SymbolKey{FileName: "ui.go", Symbol: "renderSidebar"}
+	e := &countingEmbedder{}
+
+	ix := NewVectorIndex()
+	assert.NoError(t, ix.Update(e, map[SymbolKey]string{upload: "retry upload backoff", render: "render sidebar"}))
+	assert.Equal(t, []string{"render sidebar", "retry upload backoff"}, e.embedded)
+	assert.Equal(t, "hash-256", ix.Model)
+
+	path := filepath.Join(t.TempDir(), "cache", "vectors.json")
+	assert.NoError(t, ix.Save(path))
+	loaded, err := LoadVectorIndex(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ix, loaded)
+
+	// Unchanged definitions are not embedded again, removed ones are dropped
+	e.embedded = nil
+	assert.NoError(t, loaded.Update(e, map[SymbolKey]string{upload: "retry upload with jitter"}))
+	assert.Equal(t, []string{"retry upload with jitter"}, e.embedded)
+	assert.Equal(t, 1, loaded.Len())
+
+	q, _ := e.Embed([]string{"upload jitter"})
+	assert.Greater(t, loaded.Similarities(q[0])[upload], 0.5)
+
+	// Another embedder embeds everything again
+	e.embedded = nil
+	e.Dimensions = 32
+	assert.NoError(t, loaded.Update(e, map[SymbolKey]string{upload: "retry upload with jitter"}))
+	assert.Equal(t, []string{"retry upload with jitter"}, e.embedded)
+
+	e.err = errors.New("offline")
+	assert.Error(t, NewVectorIndex().Update(e, map[SymbolKey]string{upload: "retry"}))
+
+	empty, err := LoadVectorIndex(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+}
+
+// TestSemanticRanking verifies definitions whose body relates to the query
+// rank first, even when their name and doc comment do not match it.
+func TestSemanticRanking(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":   "package app\n\nfunc run() {\n\tformatOutput()\n\tformatOutput()\n\tsendPayload()\n}\n",
+		"cli.go":    "package app\n\nfunc main() {\n\tformatOutput()\n\tsendPayload()\n}\n",
+		"format.go": "package app\n\nfunc formatOutput() {}\n",
+		"client.go": "package app\n\nfunc sendPayload() {\n\twaitExponentialBackoff()\n\tscheduleRetry()\n}\n",
+	})
+
+	cache := filepath.Join(t.TempDir(), "vectors.json")
+	e := &countingEmbedder{}
+	r := NewRepoMap(root, nil, WithEmbedder(e), WithEmbeddingCache(cache))
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+
+	r.query = "exponential backoff retry"
+	ranked := r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "sendPayload", ranked[0].Name)
+
+	// The definitions are read from the cache the next time
+	embedded := len(e.embedded)
+	loaded, err := LoadVectorIndex(cache)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, loaded.Len())
+	}
+	r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, embedded+1, len(e.embedded))
+
+	// Without an embedder only names, doc comments and paths match
+	r = NewRepoMap(root, nil)
+	r.query = "exponential backoff retry"
+	ranked = r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "formatOutput", ranked[0].Name)
+}
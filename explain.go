@@ -0,0 +1,388 @@
+package germ
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rankTrace records the inputs and scores of the last ranking, see Explain.
+type rankTrace struct {
+	allTags         []Tag
+	mentionedFnames map[string]bool
+	mentionedIdents map[string]bool
+	symbolRanking   bool
+	// personalized is set when the ranker used the personalization
+	personalized bool
+	// nodeScores are the ranker scores of the graph nodes, file nodes having
+	// an empty symbol
+	nodeScores map[SymbolKey]float64
+	// personalization is the share of each node in the personalization
+	personalization map[SymbolKey]float64
+	// edges are the weighted edges of the ranked graph
+	edges []tracedEdge
+	// query and relevance are the query blended into the ranking, if any, and
+	// the relevance of the definitions to it
+	query     string
+	relevance map[SymbolKey]float64
+	// ranked are the ranked definitions, best first
+	ranked []DefRank
+	// fitted is set once the map is fitted to maxMapTokens, with the
+	// definitions shown
+	fitted       bool
+	maxMapTokens int
+	shown        map[SymbolKey]struct{}
+}
+
+// IncomingEdge is a graph edge pointing to an explained file or definition.
+type IncomingEdge struct {
+	// FileName is the source file, and Symbol the source definition with
+	// symbol ranking, "" for a file node
+	FileName string
+	Symbol   string
+	// Via is the referenced symbol, or the kind of a file edge, eg. import
+	Via    string
+	Weight float64
+}
+
+// Multiplier is a factor applied to the rank flowing to a file or a
+// definition.
+type Multiplier struct {
+	Name  string
+	Value float64
+}
+
+// Explanation reports why a file or a definition ranks where it does in the
+// last generated map.
+type Explanation struct {
+	FileName string
+	// Symbol is the qualified name of the definition, "" for a file
+	Symbol string
+	// Score is the ranker score of the graph node: the file, or the
+	// definition with symbol ranking, see WithSymbolRanking
+	Score float64
+	// Personalization is the share of the node in the personalization
+	Personalization float64
+	// Rank is the final rank of the definition, or of the best definition of
+	// the file, and Position its 1-based position, 0 when not ranked
+	Rank     float64
+	Position int
+	// Relevance is the relevance to the query, if any, see GenerateWithQuery
+	Relevance  float64
+	Visibility string
+	// Incoming are the edges pointing to the node, by decreasing weight
+	Incoming    []IncomingEdge
+	Multipliers []Multiplier
+	// Included is set when the map shows the file or definition, and Reason
+	// tells why it is shown or not
+	Included bool
+	Reason   string
+}
+
+// Explain reports how the file, relative to the root, or the definitions
+// named target, by bare or qualified name, were ranked in the last generated
+// map: their score, personalization, incoming edges, multipliers, and why the
+// token budget kept or cut them.
+func (r *RepoMap) Explain(target string) ([]Explanation, error) {
+	t := r.lastRanking
+	if t == nil {
+		return nil, errors.New("nothing to explain: generate a map first")
+	}
+
+	rel := target
+	if filepath.IsAbs(target) {
+		rel = r.GetRelFname(target)
+	}
+	rel = filepath.Clean(rel)
+	if _, ok := t.nodeScores[SymbolKey{FileName: rel}]; ok {
+		return []Explanation{r.explainFile(t, rel)}, nil
+	}
+
+	var keys []SymbolKey
+	seen := make(map[SymbolKey]struct{})
+	for _, tag := range t.allTags {
+		if tag.Kind != TagKindDef {
+			continue
+		}
+		k := SymbolKey{FileName: r.GetRelFname(tag.FilePath), Symbol: tag.QualifiedName()}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		if k.Symbol == target || strings.HasSuffix(k.Symbol, "."+target) {
+			seen[k] = struct{}{}
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("unknown file or definition: %s", target)
+	}
+	sortSymbolKeys(keys)
+
+	explanations := make([]Explanation, 0, len(keys))
+	for _, k := range keys {
+		explanations = append(explanations, r.explainSymbol(t, k))
+	}
+	return explanations, nil
+}
+
+// explainFile explains the ranking of a file.
+func (r *RepoMap) explainFile(t *rankTrace, rel string) Explanation {
+	node := SymbolKey{FileName: rel}
+	e := Explanation{
+		FileName:        rel,
+		Score:           t.nodeScores[node],
+		Personalization: t.personalization[node],
+		Multipliers:     r.fileMultipliers(t, rel),
+	}
+
+	for _, edge := range t.edges {
+		if edge.dst == node || (!t.symbolRanking && edge.dst.FileName == rel) {
+			e.Incoming = append(e.Incoming, edge.IncomingEdge)
+		}
+	}
+	sortIncoming(e.Incoming)
+
+	for i, dr := range t.ranked {
+		if dr.fname == rel {
+			e.Rank, e.Position = dr.rank, i+1
+			break
+		}
+	}
+	for k := range t.shown {
+		if k.FileName == rel {
+			e.Included = true
+			break
+		}
+	}
+	e.Reason = t.reason(e.Position, e.Included, "none of its definitions is referenced")
+	return e
+}
+
+// explainSymbol explains the ranking of a definition.
+func (r *RepoMap) explainSymbol(t *rankTrace, k SymbolKey) Explanation {
+	node := SymbolKey{FileName: k.FileName}
+	if t.symbolRanking {
+		node = k
+	}
+
+	var defs []Tag
+	for _, tag := range t.allTags {
+		if tag.Kind == TagKindDef && tag.QualifiedName() == k.Symbol && r.GetRelFname(tag.FilePath) == k.FileName {
+			defs = append(defs, tag)
+		}
+	}
+	info := definitionInfo(defs)
+
+	cfg := r.ranking()
+	e := Explanation{
+		FileName:        k.FileName,
+		Symbol:          k.Symbol,
+		Score:           t.nodeScores[node],
+		Personalization: t.personalization[node],
+		Relevance:       t.relevance[k],
+		Visibility:      info.visibility,
+	}
+	e.Multipliers = append([]Multiplier{{
+		Name:  identWeightName(k.Symbol, info, t.mentionedIdents),
+		Value: cfg.identWeight(k.Symbol, info, t.mentionedIdents),
+	}}, r.fileMultipliers(t, k.FileName)...)
+
+	for _, edge := range t.edges {
+		switch {
+		case t.symbolRanking && edge.dst == k,
+			!t.symbolRanking && edge.dst.FileName == k.FileName && edge.Via == k.Symbol:
+			e.Incoming = append(e.Incoming, edge.IncomingEdge)
+		}
+	}
+	sortIncoming(e.Incoming)
+
+	for i, dr := range t.ranked {
+		if dr.fname == k.FileName && dr.symbol == k.Symbol {
+			e.Rank, e.Position = dr.rank, i+1
+			break
+		}
+	}
+	_, e.Included = t.shown[k]
+	e.Reason = t.reason(e.Position, e.Included, "no reference resolves to it")
+	return e
+}
+
+// reason tells why a ranked item at the given position is shown or not.
+func (t *rankTrace) reason(position int, included bool, unranked string) string {
+	switch {
+	case position == 0:
+		return "not ranked: " + unranked
+	case !t.fitted:
+		return fmt.Sprintf("ranked %d of %d", position, len(t.ranked))
+	case included:
+		return fmt.Sprintf("shown: ranked %d of %d, within the budget of %d tokens", position, len(t.ranked), t.maxMapTokens)
+	default:
+		return fmt.Sprintf("cut by the token budget: ranked %d of %d, the map of at most %d tokens shows %d definitions",
+			position, len(t.ranked), t.maxMapTokens, len(t.shown))
+	}
+}
+
+// fileMultipliers returns the multipliers applied to the rank of a file.
+func (r *RepoMap) fileMultipliers(t *rankTrace, rel string) []Multiplier {
+	var m []Multiplier
+	if t.mentionedFnames[rel] {
+		m = append(m, Multiplier{Name: "chat file personalization", Value: r.ranking().ChatFileWeight})
+	}
+	if boost := r.historyBoost(rel); boost != 1 {
+		m = append(m, Multiplier{Name: "git history reference boost", Value: boost})
+	}
+	if boost := r.proximityBoost(rel); boost != 1 && t.personalized {
+		m = append(m, Multiplier{Name: "directory proximity personalization", Value: boost})
+	}
+	return m
+}
+
+// identWeightName names the identifier weight applied to a symbol, see
+// RankingConfig.identWeight.
+func identWeightName(symbol string, info symbolInfo, mentionedIdents map[string]bool) string {
+	switch {
+	case mentionedIdents[symbol] || mentionedIdents[info.name]:
+		return "mentioned identifier"
+	case info.visibility != "":
+		return info.visibility + " identifier"
+	case strings.HasPrefix(info.name, "_"):
+		return "underscore identifier"
+	default:
+		return "identifier"
+	}
+}
+
+// tracedEdge is an incoming edge with its destination node.
+type tracedEdge struct {
+	IncomingEdge
+	dst SymbolKey
+}
+
+// edgeTrace sums the weights of the edges of a ranked graph by source,
+// destination, and referenced symbol or kind, for Explain.
+type edgeTrace map[tracedEdge]float64
+
+// add records an edge of the graph.
+func (t edgeTrace) add(src, dst SymbolKey, via string, w float64) {
+	t[tracedEdge{IncomingEdge: IncomingEdge{FileName: src.FileName, Symbol: src.Symbol, Via: via}, dst: dst}] += w
+}
+
+// addFileEdges records the edges of the file graph, references by the
+// symbol they reference and other edges by their kind.
+func (t edgeTrace) addFileEdges(edges []fileEdge) {
+	for _, e := range edges {
+		via := e.kind
+		if e.kind == "reference" {
+			via = e.symbol
+		}
+		t.add(SymbolKey{FileName: e.src}, SymbolKey{FileName: e.dst}, via, e.weight)
+	}
+}
+
+// edges returns the summed edges.
+func (t edgeTrace) edges() []tracedEdge {
+	edges := make([]tracedEdge, 0, len(t))
+	for e, w := range t {
+		e.Weight = w
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// sortIncoming sorts edges by decreasing weight, then source.
+func sortIncoming(edges []IncomingEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Weight != edges[j].Weight {
+			return edges[i].Weight > edges[j].Weight
+		}
+		if edges[i].FileName != edges[j].FileName {
+			return edges[i].FileName < edges[j].FileName
+		}
+		if edges[i].Symbol != edges[j].Symbol {
+			return edges[i].Symbol < edges[j].Symbol
+		}
+		return edges[i].Via < edges[j].Via
+	})
+}
+
+// traceRanking records a ranking for Explain: the scores of the graph
+// nodes, their personalization, normalized to shares, the edges of the
+// graph, and the ranked definitions.
+func (r *RepoMap) traceRanking(
+	allTags []Tag,
+	mentionedFnames, mentionedIdents map[string]bool,
+	nodeScores, personalization map[SymbolKey]float64,
+	edges edgeTrace,
+	relevance map[SymbolKey]float64,
+	ranked []DefRank,
+) {
+	total := 0.0
+	for _, p := range personalization {
+		total += p
+	}
+	shares := make(map[SymbolKey]float64, len(personalization))
+	for k, p := range personalization {
+		if total > 0 {
+			shares[k] = p / total
+		}
+	}
+
+	r.lastRanking = &rankTrace{
+		allTags:         allTags,
+		mentionedFnames: mentionedFnames,
+		mentionedIdents: mentionedIdents,
+		symbolRanking:   r.symbolRanking,
+		personalized:    personalized(r.ranker()),
+		nodeScores:      nodeScores,
+		personalization: shares,
+		edges:           edges.edges(),
+		query:           r.query,
+		relevance:       relevance,
+		ranked:          ranked,
+	}
+}
+
+// traceBudget records the definitions shown by the map fitted to
+// maxMapTokens, for Explain.
+func (r *RepoMap) traceBudget(shown []Tag, maxMapTokens int) {
+	t := r.lastRanking
+	if t == nil {
+		return
+	}
+	t.fitted = true
+	t.maxMapTokens = maxMapTokens
+	t.shown = make(map[SymbolKey]struct{})
+	for _, tag := range shown {
+		if tag.Kind == TagKindDef {
+			t.shown[SymbolKey{FileName: r.GetRelFname(tag.FilePath), Symbol: tag.QualifiedName()}] = struct{}{}
+		}
+	}
+}
+
+// String formats the explanation for humans.
+func (e Explanation) String() string {
+	var b strings.Builder
+	name := e.FileName
+	if e.Symbol != "" {
+		name += " " + e.Symbol
+	}
+	fmt.Fprintf(&b, "%s: %s\n", name, e.Reason)
+	fmt.Fprintf(&b, "  score %.6f, personalization %.6f, rank %.6f", e.Score, e.Personalization, e.Rank)
+	if e.Relevance > 0 {
+		fmt.Fprintf(&b, ", query relevance %.3f", e.Relevance)
+	}
+	b.WriteString("\n")
+	for _, m := range e.Multipliers {
+		fmt.Fprintf(&b, "  x%.3g %s\n", m.Value, m.Name)
+	}
+	for _, in := range e.Incoming {
+		src := in.FileName
+		if in.Symbol != "" {
+			src += " " + in.Symbol
+		}
+		fmt.Fprintf(&b, "  <- %s via %s (%.3f)\n", src, in.Via, in.Weight)
+	}
+	return b.String()
+}
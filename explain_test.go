@@ -0,0 +1,131 @@
+package germ
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// explainTestFiles reference parseLedger from two files and exportReport
+// from one, padded so a small budget cannot show both definitions.
+func explainTestFiles(t *testing.T) (string, []string) {
+	root := t.TempDir()
+	pad := strings.Repeat("\t// filler\n", 30)
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":    "package app\n\nfunc run() {\n\tparseLedger()\n\texportReport()\n}\n",
+		"cli.go":     "package app\n\nfunc cli() {\n\tparseLedger()\n}\n",
+		"ledger.go":  "package app\n\nfunc parseLedger() {\n" + pad + "}\n",
+		"reports.go": "package app\n\nfunc exportReport() {\n" + pad + "}\n",
+	})
+	return root, fnames
+}
+
+func TestExplain(t *testing.T) {
+	root, fnames := explainTestFiles(t)
+	r := NewRepoMap(root, nil)
+
+	_, err := r.Explain("ledger.go")
+	assert.Error(t, err)
+
+	out := r.GetRankedTagsMap(nil, fnames, 60, map[string]bool{"cli.go": true}, map[string]bool{})
+	assert.Contains(t, out, "parseLedger")
+	assert.NotContains(t, out, "exportReport")
+
+	t.Run("file", func(t *testing.T) {
+		e, err := r.Explain("ledger.go")
+		if !assert.NoError(t, err) || !assert.Len(t, e, 1) {
+			return
+		}
+		assert.Equal(t, "ledger.go", e[0].FileName)
+		assert.Equal(t, 1, e[0].Position)
+		assert.True(t, e[0].Included)
+		assert.Greater(t, e[0].Score, 0.0)
+		assert.Equal(t, []string{"cli.go", "main.go"}, incomingFiles(e[0].Incoming))
+		assert.Equal(t, "app.parseLedger", e[0].Incoming[0].Via)
+		assert.Contains(t, e[0].Reason, "shown")
+
+		// The chat file is personalized
+		e, _ = r.Explain("cli.go")
+		assert.Equal(t, []Multiplier{{Name: "chat file personalization", Value: 100}}, e[0].Multipliers)
+		assert.Greater(t, e[0].Personalization, 0.9)
+		assert.Equal(t, 0, e[0].Position)
+		assert.Contains(t, e[0].Reason, "not ranked")
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		e, err := r.Explain("exportReport")
+		if !assert.NoError(t, err) || !assert.Len(t, e, 1) {
+			return
+		}
+		assert.Equal(t, "app.exportReport", e[0].Symbol)
+		assert.Equal(t, 2, e[0].Position)
+		assert.False(t, e[0].Included)
+		assert.Contains(t, e[0].Reason, "cut by the token budget")
+		assert.Equal(t, "package identifier", e[0].Multipliers[0].Name)
+		assert.Equal(t, 0.5, e[0].Multipliers[0].Value)
+		assert.Equal(t, []IncomingEdge{{FileName: "main.go", Via: "app.exportReport", Weight: 0.5}}, e[0].Incoming)
+		assert.Contains(t, e[0].String(), "<- main.go via app.exportReport")
+	})
+
+	_, err = r.Explain("missingSymbol")
+	assert.Error(t, err)
+}
+
+// TestExplainSymbolRanking verifies definitions are explained by their own
+// node with symbol ranking.
+func TestExplainSymbolRanking(t *testing.T) {
+	root, fnames := explainTestFiles(t)
+	r := NewRepoMap(root, nil, WithSymbolRanking(true))
+	r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+
+	e, err := r.Explain("app.parseLedger")
+	if !assert.NoError(t, err) || !assert.Len(t, e, 1) {
+		return
+	}
+	assert.Equal(t, 1, e[0].Position)
+	assert.True(t, e[0].Included)
+	assert.Contains(t, e[0].Reason, "shown")
+	assert.Equal(t, []IncomingEdge{
+		{FileName: "ledger.go", Via: "contains", Weight: 1},
+		{FileName: "cli.go", Symbol: "app.cli", Via: "app.parseLedger", Weight: 0.7071067811865476},
+		{FileName: "main.go", Symbol: "app.run", Via: "app.parseLedger", Weight: 0.7071067811865476},
+	}, e[0].Incoming)
+}
+
+// TestExplainRecordedEdges verifies the incoming edges are the ones of the
+// ranked graph, self-loops included, rather than recomputed from the current
+// config.
+func TestExplainRecordedEdges(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":  "package app\n\nfunc run() {\n\tretry()\n}\n",
+		"retry.go": "package app\n\nfunc retry() {\n\tretry()\n}\n",
+	})
+	r := NewRepoMap(root, nil)
+	r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+
+	cfg := DefaultRankingConfig()
+	cfg.ReferenceWeight = func(float64) float64 { return 0 }
+	r.rankingConfig = &cfg
+
+	e, err := r.Explain("retry")
+	if !assert.NoError(t, err) || !assert.Len(t, e, 1) {
+		return
+	}
+	assert.Equal(t, []IncomingEdge{
+		{FileName: "main.go", Via: "app.retry", Weight: 0.7071067811865476},
+		{FileName: "retry.go", Via: "app.retry", Weight: 0.7071067811865476},
+	}, e[0].Incoming)
+}
+
+// incomingFiles returns the distinct source files of edges, sorted.
+func incomingFiles(edges []IncomingEdge) []string {
+	var files []string
+	for _, e := range edges {
+		files = appendUnique(files, e.FileName)
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,207 @@
+package germ
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FileHistory holds the git history signals of a file over a time window.
+type FileHistory struct {
+	// LastModified is the time of the last commit touching the file
+	LastModified time.Time
+	// Commits is the number of commits touching the file
+	Commits int
+	// AuthoredCommits is the number of those commits authored by the current
+	// git user
+	AuthoredCommits int
+}
+
+// GitHistory holds the history of the files of a repository.
+type GitHistory struct {
+	// Files maps paths relative to the RepoMap root to their history
+	Files map[string]FileHistory
+	// Since and Until bound the window the history covers
+	Since time.Time
+	Until time.Time
+	// CoChanges counts the files committed together over the window
+	CoChanges *CoChanges
+	// maxCommits is the highest commit count of a file
+	maxCommits int
+}
+
+// commitMarker starts each commit header in the git log output, written as
+// %x00 in the log format. With -z, it shows as an empty field before the
+// header since fields are NUL terminated too.
+const commitMarker = "\x00"
+
+// GetGitHistory reads the history of the files under root over the given
+// window, ending now, with the git binary. Paths are relative to root.
+func GetGitHistory(root string, window time.Duration) (*GitHistory, error) {
+	until := time.Now()
+	since := until.Add(-window)
+
+	out, err := runGit(root, "log", "-z", "--relative", "--no-merges", "--no-renames", "--name-only",
+		"--since="+since.Format(time.RFC3339), "--format=%x00%ct%x09%ae")
+	if err != nil {
+		return nil, err
+	}
+
+	// The current user, if configured
+	email, _ := runGit(root, "config", "user.email")
+
+	h := parseGitLog(out, strings.TrimSpace(string(email)))
+	h.Since, h.Until = since, until
+	return h, nil
+}
+
+// runGit runs a git command in dir and returns its standard output.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parseGitLog parses the output of git log -z --name-only with commit headers
+// of the form commitMarker<unix time>\t<author email>, into the history of
+// each file and the files committed together. Fields, ie. headers and paths,
+// are NUL terminated and paths are not quoted.
+func parseGitLog(out []byte, email string) *GitHistory {
+	h := &GitHistory{Files: make(map[string]FileHistory), CoChanges: newCoChanges()}
+
+	var when time.Time
+	var mine bool
+	var files []string
+	header := false
+	for _, field := range strings.Split(string(out), commitMarker) {
+		switch {
+		case field == "":
+			// The commit marker, or the end of the output
+			header = true
+			continue
+
+		case header:
+			header = false
+			h.CoChanges.addCommit(files)
+			files = nil
+
+			ts, author, _ := strings.Cut(field, "\t")
+			secs, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				log.Trace().Err(err).Str("header", field).Msg("git log")
+				continue
+			}
+			when = time.Unix(secs, 0)
+			mine = email != "" && strings.EqualFold(author, email)
+			continue
+		}
+
+		// The first path of a commit follows the newline ending its header
+		name := strings.TrimPrefix(field, "\n")
+		files = appendUnique(files, name)
+
+		fh := h.Files[name]
+		fh.Commits++
+		if mine {
+			fh.AuthoredCommits++
+		}
+		if when.After(fh.LastModified) {
+			fh.LastModified = when
+		}
+		h.Files[name] = fh
+		if fh.Commits > h.maxCommits {
+			h.maxCommits = fh.Commits
+		}
+	}
+	h.CoChanges.addCommit(files)
+	return h
+}
+
+// Recency returns 1 for a file modified at the end of the window, decreasing
+// linearly to 0 for files last modified at its start or not at all.
+func (h *GitHistory) Recency(rel string) float64 {
+	fh, ok := h.Files[rel]
+	if !ok || !h.Until.After(h.Since) {
+		return 0
+	}
+	age := h.Until.Sub(fh.LastModified)
+	window := h.Until.Sub(h.Since)
+	return clamp01(1 - float64(age)/float64(window))
+}
+
+// Churn returns the commit count of a file relative to the most committed
+// file, between 0 and 1.
+func (h *GitHistory) Churn(rel string) float64 {
+	if h.maxCommits == 0 {
+		return 0
+	}
+	return float64(h.Files[rel].Commits) / float64(h.maxCommits)
+}
+
+// Authorship returns the share of the commits to a file authored by the
+// current git user, between 0 and 1.
+func (h *GitHistory) Authorship(rel string) float64 {
+	fh := h.Files[rel]
+	if fh.Commits == 0 {
+		return 0
+	}
+	return float64(fh.AuthoredCommits) / float64(fh.Commits)
+}
+
+// clamp01 bounds v to [0, 1].
+func clamp01(v float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	}
+	return v
+}
+
+// WithGitHistory boosts the files edited in the given window of the git
+// history when ranking: recently modified files, files with many commits and
+// files recently authored by the current git user rank higher, see the
+// Recency, Churn and Authorship weights of RankingConfig. Zero disables it.
+func WithGitHistory(window time.Duration) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.historyWindow = window
+	}
+}
+
+// loadHistory reads the git history when enabled, or returns nil. Failures,
+// eg. outside a git repository, disable the history signals.
+func (r *RepoMap) loadHistory() *GitHistory {
+	if r.historyWindow <= 0 {
+		return nil
+	}
+	h, err := GetGitHistory(r.root, r.historyWindow)
+	if err != nil {
+		log.Warn().Err(err).Msg("git history disabled")
+		return nil
+	}
+	return h
+}
+
+// historyBoost returns the multiplier of the reference edges to a file from
+// its git history, 1 without history.
+func (r *RepoMap) historyBoost(rel string) float64 {
+	if r.history == nil {
+		return 1
+	}
+	cfg := r.ranking()
+	return 1 +
+		cfg.RecencyWeight*r.history.Recency(rel) +
+		cfg.ChurnWeight*r.history.Churn(rel) +
+		cfg.AuthorshipWeight*r.history.Authorship(rel)
+}
@@ -0,0 +1,151 @@
+package germ
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseGitLog verifies commits are attributed to the files they touch.
+func TestParseGitLog(t *testing.T) {
+	out := "\x001700000200\tme@example.com\x00\nsrv/server.go\x00README.md\x00" +
+		"\x001700000100\tother@example.com\x00\nsrv/server.go\x00" +
+		"\x001700000050\tme@example.com\x00" +
+		"\x001700000000\tME@example.com\x00\nlegacy/old.go\x00"
+
+	h := parseGitLog([]byte(out), "me@example.com")
+
+	assert.Equal(t, FileHistory{LastModified: time.Unix(1700000200, 0), Commits: 2, AuthoredCommits: 1}, h.Files["srv/server.go"])
+	assert.Equal(t, FileHistory{LastModified: time.Unix(1700000000, 0), Commits: 1, AuthoredCommits: 1}, h.Files["legacy/old.go"])
+	assert.Equal(t, 2, h.maxCommits)
+
+	assert.Equal(t, 1.0, h.Churn("srv/server.go"))
+	assert.Equal(t, 0.5, h.Churn("README.md"))
+	assert.Equal(t, 0.5, h.Authorship("srv/server.go"))
+	assert.Equal(t, 0.0, h.Churn("missing.go"))
+
+	h.Since, h.Until = time.Unix(1700000000, 0), time.Unix(1700000200, 0)
+	assert.Equal(t, 1.0, h.Recency("srv/server.go"))
+	assert.Equal(t, 0.0, h.Recency("legacy/old.go"))
+	assert.Equal(t, 0.0, h.Recency("missing.go"))
+}
+
+// gitInit creates a git repository for me@example.com, or skips the test
+// without git.
+func gitInit(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	if out, err := exec.Command("git", "-C", repo, "config", "user.email", "me@example.com").CombinedOutput(); err != nil {
+		t.Fatalf("git config: %v: %s", err, out)
+	}
+	return repo
+}
+
+// gitCommit commits the given files with the given author and date.
+func gitCommit(t *testing.T, root, email string, when time.Time, files map[string]string) {
+	t.Helper()
+	writeTestFiles(t, root, files)
+
+	date := when.Format(time.RFC3339)
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "change"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=dev", "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=dev", "GIT_COMMITTER_EMAIL="+email, "GIT_COMMITTER_DATE="+date,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+}
+
+// TestGetGitHistory verifies the history is read from git, relative to the
+// root and bounded by the window.
+func TestGetGitHistory(t *testing.T) {
+	repo := gitInit(t)
+
+	now := time.Now()
+	gitCommit(t, repo, "me@example.com", now.AddDate(-2, 0, 0), map[string]string{"app/legacy.go": "package app\n"})
+	gitCommit(t, repo, "other@example.com", now.Add(-48*time.Hour), map[string]string{"app/engine.go": "package app\n"})
+	gitCommit(t, repo, "me@example.com", now.Add(-time.Hour), map[string]string{"app/engine.go": "package app\n\n// tuned\n"})
+
+	root := filepath.Join(repo, "app")
+	h, err := GetGitHistory(root, 30*24*time.Hour)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2, h.Files["engine.go"].Commits)
+	assert.Equal(t, 1, h.Files["engine.go"].AuthoredCommits)
+	assert.NotContains(t, h.Files, "legacy.go")
+	assert.Greater(t, h.Recency("engine.go"), 0.9)
+
+	// Paths git would quote are read as is
+	gitCommit(t, repo, "me@example.com", now, map[string]string{"app/café menu.go": "package app\n"})
+	h, err = GetGitHistory(root, 30*24*time.Hour)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, h.Files["café menu.go"].Commits)
+	}
+
+	_, err = GetGitHistory(t.TempDir(), time.Hour)
+	assert.Error(t, err)
+}
+
+// TestHistoryBoost verifies actively edited files rank ahead of stable ones
+// referenced as often.
+func TestHistoryBoost(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":   "package app\n\nfunc run() {\n\tanchorWidget()\n\tworkerWidget()\n}\n",
+		"anchor.go": "package app\n\nfunc anchorWidget() {}\n",
+		"worker.go": "package app\n\nfunc workerWidget() {}\n",
+	})
+
+	r := &RepoMap{root: root}
+	assert.Equal(t, 1.0, r.historyBoost("worker.go"))
+
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	ranked := r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "anchorWidget", ranked[0].Name)
+
+	now := time.Now()
+	r.history = &GitHistory{
+		Files: map[string]FileHistory{
+			"worker.go": {LastModified: now, Commits: 3, AuthoredCommits: 3},
+		},
+		Since:      now.Add(-time.Hour),
+		Until:      now,
+		maxCommits: 3,
+	}
+	assert.Equal(t, 4.0, r.historyBoost("worker.go"))
+	assert.Equal(t, 1.0, r.historyBoost("anchor.go"))
+
+	ranked = r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "workerWidget", ranked[0].Name)
+
+	// The boost is applied once, to the reference edges
+	worker, err := r.Explain("workerWidget")
+	assert.NoError(t, err)
+	anchor, err := r.Explain("anchorWidget")
+	assert.NoError(t, err)
+	if assert.Len(t, worker, 1) && assert.Len(t, anchor, 1) {
+		assert.InDelta(t, 4.0, worker[0].Rank/anchor[0].Rank, 1e-9)
+		assert.InDelta(t, 4.0, worker[0].Incoming[0].Weight/anchor[0].Incoming[0].Weight, 1e-9)
+		assert.Equal(t, []Multiplier{{Name: "package identifier", Value: 0.5}, {Name: "git history reference boost", Value: 4}}, worker[0].Multipliers)
+	}
+
+	ranked = r.getRankedTagsBySymbolRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "workerWidget", ranked[0].Name)
+}
@@ -0,0 +1,418 @@
+package germ
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	grepast "github.com/cyber-nic/grep-ast"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Import is an import, use, mod or include directive extracted from a source file.
+type Import struct {
+	FileName string
+	FilePath string
+	Line     int
+	// Path is the specifier as written, eg. fmt, ./util, app.db or foo.h
+	Path string
+	// Kind is the capture suffix, eg. import, from, member, wildcard, static,
+	// require, use, mod, include or system
+	Kind string
+	// Lang is the language id of the importing file
+	Lang string
+}
+
+// ImportResolver maps an import to the repository files it refers to. An
+// empty result means the import refers to a package outside the repository.
+type ImportResolver interface {
+	Resolve(imp Import) []string
+}
+
+// DependencyGraph is the import graph of a set of files. File names are
+// relative to the RepoMap root.
+type DependencyGraph struct {
+	// Files maps each file to the repository files it imports.
+	Files map[string][]string
+	// Packages maps each file to the imports that did not resolve to a
+	// repository file, eg. the standard library or third party packages.
+	Packages map[string][]string
+	// Implementations maps C/C++ headers to the source files defining the
+	// symbols they declare.
+	Implementations map[string][]string
+}
+
+// Imports returns the repository files imported by fname.
+func (g *DependencyGraph) Imports(fname string) []string {
+	return g.Files[fname]
+}
+
+// ImportedBy returns the repository files importing fname.
+func (g *DependencyGraph) ImportedBy(fname string) []string {
+	var importers []string
+	for src, dsts := range g.Files {
+		for _, dst := range dsts {
+			if dst == fname {
+				importers = append(importers, src)
+				break
+			}
+		}
+	}
+	sort.Strings(importers)
+	return importers
+}
+
+// Targets narrows the files defining a symbol referenced from refFile to the
+// ones refFile imports, or implements a header it includes, plus those in its
+// own directory (same package). All defining files are returned when refFile
+// imports none of them, or when g is nil.
+func (g *DependencyGraph) Targets(refFile string, defFiles map[string]struct{}) []string {
+	all := make([]string, 0, len(defFiles))
+	for f := range defFiles {
+		all = append(all, f)
+	}
+	sort.Strings(all)
+
+	if g == nil {
+		return all
+	}
+
+	imported := make(map[string]struct{})
+	for _, f := range g.Files[refFile] {
+		imported[f] = struct{}{}
+		for _, impl := range g.Implementations[f] {
+			imported[impl] = struct{}{}
+		}
+	}
+
+	var targets []string
+	importsAny := false
+	for _, f := range all {
+		_, isImported := imported[f]
+		importsAny = importsAny || isImported
+		if isImported || f == refFile || filepath.Dir(f) == filepath.Dir(refFile) {
+			targets = append(targets, f)
+		}
+	}
+	if !importsAny {
+		return all
+	}
+	return targets
+}
+
+// fileEdge is a weighted edge between two files that does not come from a
+// shared identifier, eg. an import. symbol is the definition of dst the edge
+// ranks, if any, eg. the subtype of a relation.
+type fileEdge struct {
+	src, dst string
+	weight   float64
+	kind     string
+	symbol   string
+}
+
+// GetImportsRaw parses the file with Tree-sitter and extracts its import directives.
+func (r *RepoMap) GetImportsRaw(fname, relFname string) ([]Import, error) {
+	lang, langID, err := getLanguageFromFileName(fname, nil)
+	if err != nil || lang == nil {
+		return nil, grepast.ErrorUnsupportedLanguage
+	}
+
+	q, err := r.LoadImportsQuery(lang, langID)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	sourceCode, err := readSourceCode(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(lang)
+
+	tree := parser.Parse(sourceCode, nil)
+	if tree == nil || tree.RootNode() == nil {
+		return nil, fmt.Errorf("failed to parse file: %s", fname)
+	}
+	defer tree.Close()
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	var imports []Import
+	seen := make(map[uint]struct{})
+	captures := qc.Captures(q, tree.RootNode(), sourceCode)
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		c := match.Captures[index]
+		capture := q.CaptureNames()[c.Index]
+		if !strings.HasPrefix(capture, "import.") {
+			continue
+		}
+
+		// A node matched by several patterns keeps the first, most specific one
+		if _, ok := seen[c.Node.StartByte()]; ok {
+			continue
+		}
+		seen[c.Node.StartByte()] = struct{}{}
+
+		path := c.Node.Utf8Text(sourceCode)
+		kind := strings.TrimPrefix(capture, "import.")
+
+		// Members are qualified with the @module capture of their match, eg.
+		// `from app import db` yields app.db
+		if kind == "member" {
+			for _, mc := range match.Captures {
+				if q.CaptureNames()[mc.Index] == "module" {
+					path = joinModulePath(mc.Node.Utf8Text(sourceCode), path)
+				}
+			}
+		}
+
+		imports = append(imports, Import{
+			FileName: relFname,
+			FilePath: fname,
+			Line:     int(c.Node.StartPosition().Row),
+			Path:     path,
+			Kind:     kind,
+			Lang:     langID,
+		})
+	}
+
+	return imports, nil
+}
+
+// GetDependencyGraph extracts the imports of each file and resolves them to
+// repository files, or leaves them as external packages.
+func (r *RepoMap) GetDependencyGraph(fnames []string) *DependencyGraph {
+	rels := make([]string, len(fnames))
+	for i, fname := range fnames {
+		rels[i] = r.GetRelFname(fname)
+	}
+	resolvers := r.importResolvers(newFileIndex(r.root, rels))
+
+	g := &DependencyGraph{
+		Files:    make(map[string][]string),
+		Packages: make(map[string][]string),
+	}
+
+	for i, fname := range fnames {
+		rel := rels[i]
+
+		imps, err := r.GetImportsRaw(fname, rel)
+		if err != nil {
+			log.Trace().Err(err).Str("file", fname).Msg("imports")
+			continue
+		}
+
+		for _, imp := range imps {
+			var dsts []string
+			if res, ok := resolvers[imp.Lang]; ok {
+				dsts = res.Resolve(imp)
+			}
+
+			if len(dsts) == 0 {
+				// An unresolved member is a symbol of its module, which is
+				// already recorded
+				if imp.Kind == "member" {
+					continue
+				}
+				g.Packages[rel] = appendUnique(g.Packages[rel], imp.Path)
+				continue
+			}
+			for _, dst := range dsts {
+				if dst != rel {
+					g.Files[rel] = appendUnique(g.Files[rel], dst)
+				}
+			}
+		}
+	}
+
+	for _, m := range []map[string][]string{g.Files, g.Packages} {
+		for _, v := range m {
+			sort.Strings(v)
+		}
+	}
+
+	return g
+}
+
+// getImportEdges returns one edge per resolved file import, and one edge from
+// each C/C++ header to each of its implementations, weighted by the RepoMap
+// import weight.
+func (r *RepoMap) getImportEdges(deps *DependencyGraph) []fileEdge {
+	var edges []fileEdge
+	for src, dsts := range deps.Files {
+		for _, dst := range dsts {
+			edges = append(edges, fileEdge{src: src, dst: dst, weight: r.importWeight, kind: "import"})
+		}
+	}
+	for header, impls := range deps.Implementations {
+		for _, impl := range impls {
+			edges = append(edges, fileEdge{src: header, dst: impl, weight: r.importWeight, kind: "declaration"})
+		}
+	}
+	return edges
+}
+
+// importResolvers returns the import resolver of each language.
+func (r *RepoMap) importResolvers(idx *fileIndex) map[string]ImportResolver {
+	js := newJSImportResolver(idx)
+	c := newCIncludeResolver(idx, r.compileCommands, r.includeDirs)
+	return map[string]ImportResolver{
+		"go":         newGoImportResolver(idx),
+		"python":     newPythonImportResolver(idx, r.pythonRoots),
+		"javascript": js,
+		"typescript": js,
+		"rust":       newRustImportResolver(idx),
+		"java":       newJavaImportResolver(idx),
+		"c":          c,
+		"cpp":        c,
+	}
+}
+
+// fileIndex is the set of repository files that import resolvers match against.
+type fileIndex struct {
+	root  string
+	files map[string]struct{}
+	dirs  map[string][]string
+}
+
+// newFileIndex indexes relative file names by directory.
+func newFileIndex(root string, rels []string) *fileIndex {
+	idx := &fileIndex{
+		root:  root,
+		files: make(map[string]struct{}, len(rels)),
+		dirs:  make(map[string][]string),
+	}
+	for _, rel := range rels {
+		rel = filepath.Clean(rel)
+		if _, ok := idx.files[rel]; ok {
+			continue
+		}
+		idx.files[rel] = struct{}{}
+		dir := filepath.Dir(rel)
+		idx.dirs[dir] = append(idx.dirs[dir], rel)
+	}
+	for _, files := range idx.dirs {
+		sort.Strings(files)
+	}
+	return idx
+}
+
+// has reports whether rel is an indexed file.
+func (idx *fileIndex) has(rel string) bool {
+	_, ok := idx.files[filepath.Clean(rel)]
+	return ok
+}
+
+// first returns the first candidate that is an indexed file, if any.
+func (idx *fileIndex) first(candidates ...string) []string {
+	for _, c := range candidates {
+		if idx.has(c) {
+			return []string{filepath.Clean(c)}
+		}
+	}
+	return nil
+}
+
+// withSuffix returns the indexed files whose path ends with suffix.
+func (idx *fileIndex) withSuffix(suffix string) []string {
+	var matches []string
+	for rel := range idx.files {
+		if rel == suffix || strings.HasSuffix(rel, string(filepath.Separator)+suffix) {
+			matches = append(matches, rel)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// goImportResolver resolves Go import paths to the files of the package
+// directory, using the module path of each go.mod in the repository.
+type goImportResolver struct {
+	idx *fileIndex
+	// modules maps module paths to their relative directory
+	modules map[string]string
+}
+
+func newGoImportResolver(idx *fileIndex) *goImportResolver {
+	res := &goImportResolver{idx: idx, modules: make(map[string]string)}
+
+	dirs := []string{"."}
+	for dir := range idx.dirs {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		if mod := readGoModulePath(filepath.Join(idx.root, dir, "go.mod")); mod != "" {
+			res.modules[mod] = dir
+		}
+	}
+	return res
+}
+
+// Resolve returns the non-test Go files of the imported package directory.
+func (res *goImportResolver) Resolve(imp Import) []string {
+	// Pick the longest matching module path to support nested modules
+	var modPath, modDir string
+	for mod, dir := range res.modules {
+		if (imp.Path == mod || strings.HasPrefix(imp.Path, mod+"/")) && len(mod) > len(modPath) {
+			modPath, modDir = mod, dir
+		}
+	}
+	if modPath == "" {
+		return nil
+	}
+
+	pkgDir := filepath.Join(modDir, filepath.FromSlash(strings.TrimPrefix(imp.Path, modPath)))
+
+	var files []string
+	for _, f := range res.idx.dirs[filepath.Clean(pkgDir)] {
+		if filepath.Ext(f) == ".go" && !strings.HasSuffix(f, "_test.go") {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// goMajorVersion matches the major version ending a Go import path, eg. /v5
+// in github.com/jackc/pgx/v5 or .v3 in gopkg.in/yaml.v3.
+var goMajorVersion = regexp.MustCompile(`[/.]v[0-9]+$`)
+
+// goPackageName returns the package name an import path is referred to by,
+// by convention its last element without major version or go- prefix, eg.
+// log for github.com/rs/zerolog/log, pgx for github.com/jackc/pgx/v5 or
+// isatty for github.com/mattn/go-isatty.
+func goPackageName(importPath string) string {
+	name := path.Base(goMajorVersion.ReplaceAllString(importPath, ""))
+	if s, ok := strings.CutPrefix(name, "go-"); ok && s != "" {
+		name = s
+	}
+	return name
+}
+
+// readGoModulePath returns the module path declared by a go.mod file, if any.
+func readGoModulePath(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		}
+	}
+	return ""
+}
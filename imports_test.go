@@ -0,0 +1,83 @@
+package germ
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFiles writes files (relative name -> content) under root and
+// returns their absolute paths.
+func writeTestFiles(t *testing.T, root string, files map[string]string) []string {
+	t.Helper()
+
+	var fnames []string
+	for rel, content := range files {
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+		fnames = append(fnames, p)
+	}
+	return fnames
+}
+
+// TestGetDependencyGraph verifies imports are resolved to repository files
+// per language, and unresolved imports are kept as packages.
+func TestGetDependencyGraph(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"go.mod":                     "module example.com/app\n\ngo 1.23\n",
+		"cmd/main.go":                "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/server\"\n)\n\nfunc main() { fmt.Println(server.Start()) }\n",
+		"server/server.go":           "package server\n\nfunc Start() int { return 1 }\n",
+		"server/util.go":             "package server\n\nfunc util() {}\n",
+		"server/srv_test.go":         "package server\n",
+		"app/__init__.py":            "",
+		"app/db.py":                  "class Session:\n    pass\n",
+		"app/api.py":                 "from .db import Session\nimport os\n",
+		"web/index.ts":               "import { a } from './util';\nimport React from 'react';\n",
+		"web/util/index.ts":          "export const a = 1;\n",
+		"src/lib.rs":                 "mod net;\n",
+		"src/net/mod.rs":             "pub fn serve() {}\n",
+		"java/com/x/App.java":        "package com.x;\nimport com.x.model.User;\nclass App {}\n",
+		"java/com/x/model/User.java": "package com.x.model;\nclass User {}\n",
+		"c/main.c":                   "#include \"util.h\"\n#include <stdio.h>\nint main() { return 0; }\n",
+		"c/util.h":                   "int util(void);\n",
+	})
+
+	r := &RepoMap{root: root}
+	deps := r.GetDependencyGraph(fnames)
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"cmd/main.go", []string{"server/server.go", "server/util.go"}},
+		{"app/api.py", []string{"app/db.py"}},
+		{"web/index.ts", []string{"web/util/index.ts"}},
+		{"src/lib.rs", []string{"src/net/mod.rs"}},
+		{"java/com/x/App.java", []string{"java/com/x/model/User.java"}},
+		{"c/main.c", []string{"c/util.h"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, deps.Imports(tt.file), "Unexpected imports for %s", tt.file)
+	}
+
+	assert.Equal(t, []string{"fmt"}, deps.Packages["cmd/main.go"])
+	assert.Equal(t, []string{"os"}, deps.Packages["app/api.py"])
+	assert.Equal(t, []string{"react"}, deps.Packages["web/index.ts"])
+	assert.Equal(t, []string{"<stdio.h>"}, deps.Packages["c/main.c"])
+	assert.Equal(t, []string{"cmd/main.go"}, deps.ImportedBy("server/server.go"))
+
+	t.Run("ImportEdges", func(t *testing.T) {
+		r := &RepoMap{root: root, importWeight: 2}
+		edges := r.getFileEdges(fnames, nil)
+
+		assert.Contains(t, edges, fileEdge{src: "app/api.py", dst: "app/db.py", weight: 2, kind: "import"})
+	})
+}
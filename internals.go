@@ -17,6 +17,16 @@ func uniqueElements(slices ...[]string) []string {
 	return result
 }
 
+// appendUnique appends elem to slice unless it is already present.
+func appendUnique(slice []string, elem string) []string {
+	for _, e := range slice {
+		if e == elem {
+			return slice
+		}
+	}
+	return append(slice, elem)
+}
+
 // filterImportantFiles is a stub to mimic Python's `filter_important_files`.
 func filterImportantFiles(files []string) []string {
 	return files
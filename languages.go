@@ -0,0 +1,138 @@
+package germ
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	grepast "github.com/cyber-nic/grep-ast"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	sitter_bash "github.com/tree-sitter/tree-sitter-bash/bindings/go"
+	sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+	sitter_cpp "github.com/tree-sitter/tree-sitter-cpp/bindings/go"
+)
+
+// extraExtensions maps the file extensions that grep-ast does not parse to the
+// grammars bundled by germ.
+var extraExtensions = map[string]string{
+	".sh":  "bash",
+	".zsh": "bash",
+	".c":   "c",
+	".h":   "c",
+	".cc":  "cpp",
+	".cpp": "cpp",
+	".cxx": "cpp",
+	".hh":  "cpp",
+	".hpp": "cpp",
+	".hxx": "cpp",
+}
+
+// cppHeaderPattern matches the C++ constructs of a .h header that the C
+// grammar cannot parse.
+var cppHeaderPattern = regexp.MustCompile(
+	`(?m)^\s*(?:(?:class|namespace)\s+\w+|template\s*<|(?:public|private|protected)\s*:|using\s+namespace\s)`)
+
+// getLanguageFromFileName maps a file name to its tree-sitter language and
+// language id, falling back to grep-ast for everything germ does not bundle.
+// The source code of .h headers, read from fname when nil, tells C++ headers
+// from C ones.
+func getLanguageFromFileName(fname string, code []byte) (*sitter.Language, string, error) {
+	ext := strings.ToLower(filepath.Ext(fname))
+	langID := extraExtensions[ext]
+	if ext == ".h" {
+		if code == nil {
+			code, _ = os.ReadFile(fname)
+		}
+		if cppHeaderPattern.Match(code) {
+			langID = "cpp"
+		}
+	}
+
+	switch langID {
+	case "bash":
+		return sitter.NewLanguage(sitter_bash.Language()), langID, nil
+	case "c":
+		return sitter.NewLanguage(sitter_c.Language()), langID, nil
+	case "cpp":
+		return sitter.NewLanguage(sitter_cpp.Language()), langID, nil
+	}
+	return grepast.GetLanguageFromFileName(fname)
+}
+
+// renderScopes renders the lines of interest of a file grep-ast cannot parse,
+// the way renderTree does: each line padded by padding lines, below the first
+// line of its enclosing scopes, and with single line gaps closed.
+func renderScopes(lang *sitter.Language, code []byte, linesOfInterest []int, padding int) (string, error) {
+	if len(linesOfInterest) == 0 {
+		return "", nil
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(lang); err != nil {
+		return "", fmt.Errorf("failed to set language: %w", err)
+	}
+	tree := parser.Parse(code, nil)
+	if tree == nil {
+		return "", fmt.Errorf("failed to parse code")
+	}
+	defer tree.Close()
+
+	lines := strings.Split(strings.TrimRight(string(code), "\n"), "\n")
+	show := make(map[int]struct{})
+	for _, loi := range linesOfInterest {
+		for i := loi - padding; i <= loi+padding; i++ {
+			if i >= 0 && i < len(lines) {
+				show[i] = struct{}{}
+			}
+		}
+	}
+
+	// Show the first line of the scopes enclosing a line of interest, except
+	// the ones starting the file
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		start, end := int(n.StartPosition().Row), int(n.EndPosition().Row)
+		if start > 0 && end > start && start < len(lines) {
+			for _, loi := range linesOfInterest {
+				if loi > start && loi <= end {
+					show[start] = struct{}{}
+					break
+				}
+			}
+		}
+		for i := uint(0); i < n.ChildCount(); i++ {
+			walk(n.Child(i))
+		}
+	}
+	walk(tree.RootNode())
+
+	shown := make([]int, 0, len(show))
+	for i := range show {
+		shown = append(shown, i)
+	}
+	sort.Ints(shown)
+	for i := 0; i+1 < len(shown); i++ {
+		if shown[i+1]-shown[i] == 2 {
+			show[shown[i]+1] = struct{}{}
+		}
+	}
+
+	var sb strings.Builder
+	ellipsis := true
+	for i, line := range lines {
+		if _, ok := show[i]; !ok {
+			if ellipsis {
+				sb.WriteString("⋮...\n")
+				ellipsis = false
+			}
+			continue
+		}
+		sb.WriteString("│" + line + "\n")
+		ellipsis = true
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,84 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetLanguageFromFileName verifies .h headers declaring C++ are parsed
+// as C++.
+func TestGetLanguageFromFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		fname string
+		code  string
+		want  string
+	}{
+		{"c source", "util.c", "int add(int a, int b);\n", "c"},
+		{"c header", "util.h", "struct point { int x; };\nint add(int a, int b);\n", "c"},
+		{"cpp class", "shape.h", "#pragma once\nclass Shape {\npublic:\n  virtual double area() const = 0;\n};\n", "cpp"},
+		{"cpp namespace", "geo.h", "namespace geo {\nint add(int a, int b);\n}\n", "cpp"},
+		{"cpp template", "box.h", "template <typename T>\nstruct Box { T v; };\n", "cpp"},
+		{"cpp source", "shape.cc", "", "cpp"},
+		{"shell script", "deploy.sh", "", "bash"},
+		{"zsh script", "setup.zsh", "", "bash"},
+		{"grep-ast", "main.go", "", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, langID, err := getLanguageFromFileName(tt.fname, []byte(tt.code))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, langID)
+		})
+	}
+}
+
+// TestCRepoMap verifies the map shows the code of C files, which grep-ast
+// cannot render.
+func TestCRepoMap(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"include/util.h": "#ifndef UTIL_H\n#define UTIL_H\n\nint add_numbers(int a, int b);\n\n#endif\n",
+		"src/util.c":     "#include \"util.h\"\n\nint add_numbers(int a, int b)\n{\n\tint sum = a + b;\n\treturn sum;\n}\n",
+		"src/main.c":     "#include \"util.h\"\n\nint main(void)\n{\n\treturn add_numbers(1, 2);\n}\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	out := r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+	assert.Contains(t, out, "src/util.c:\n")
+	assert.Contains(t, out, "│int add_numbers(int a, int b)\n")
+	assert.Contains(t, out, "include/util.h:\n")
+	assert.Contains(t, out, "│int add_numbers(int a, int b);\n")
+
+	rendered, err := r.renderTree("src/util.c", []byte("int x;\n\nstatic int twice(int v)\n{\n\tint w = v;\n\tw += v;\n\n\n\treturn w;\n}\n"), []int{8})
+	assert.NoError(t, err)
+	assert.Equal(t, "⋮...\n│static int twice(int v)\n│{\n⋮...\n│\n│\n│\treturn w;\n│}\n", rendered)
+}
+
+// TestShellScriptTags verifies .sh scripts are tagged and shown in the map.
+func TestShellScriptTags(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"scripts/lib.sh":    "#!/bin/sh\n\n# upload_artifacts copies the build output\nupload_artifacts() {\n\tcp -r dist \"$1\"\n}\n",
+		"scripts/deploy.sh": "#!/bin/sh\n. ./lib.sh\n\nupload_artifacts /srv/www\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	var defs, refs []string
+	for _, tag := range r.getTagsFromFiles(fnames, commonWords) {
+		switch tag.Kind {
+		case TagKindDef:
+			defs = append(defs, tag.Name)
+		case TagKindRef:
+			refs = append(refs, tag.Name)
+		}
+	}
+	assert.Equal(t, []string{"upload_artifacts"}, defs)
+	assert.Contains(t, refs, "upload_artifacts")
+
+	out := r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+	assert.Contains(t, out, "scripts/lib.sh:\n")
+	assert.Contains(t, out, "│upload_artifacts() {\n")
+}
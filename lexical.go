@@ -0,0 +1,331 @@
+package germ
+
+import (
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BM25 parameters: term frequency saturation and document length
+// normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopWords are the words of natural-language queries too common to tell
+// definitions apart
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {},
+	"do": {}, "does": {}, "for": {}, "from": {}, "how": {}, "in": {}, "is": {}, "it": {},
+	"of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "this": {}, "to": {}, "what": {},
+	"when": {}, "where": {}, "which": {}, "who": {}, "why": {}, "with": {},
+}
+
+// LexicalIndex is a BM25 index of the definitions of a set of files.
+type LexicalIndex struct {
+	// postings maps each term to the definitions containing it and its
+	// frequency in each
+	postings map[string]map[SymbolKey]int
+	// lengths is the number of terms of each definition
+	lengths map[SymbolKey]int
+	// totalLength is the number of terms of all definitions
+	totalLength int
+}
+
+// LexicalMatch is a definition matching a query.
+type LexicalMatch struct {
+	Symbol SymbolKey
+	Score  float64
+}
+
+// NewLexicalIndex returns an empty lexical index.
+func NewLexicalIndex() *LexicalIndex {
+	return &LexicalIndex{
+		postings: make(map[string]map[SymbolKey]int),
+		lengths:  make(map[SymbolKey]int),
+	}
+}
+
+// Add indexes the terms of text for the definition k, in addition to the ones
+// already indexed for it.
+func (ix *LexicalIndex) Add(k SymbolKey, text string) {
+	if _, ok := ix.lengths[k]; !ok {
+		ix.lengths[k] = 0
+	}
+	for _, term := range lexicalTerms(text) {
+		p := ix.postings[term]
+		if p == nil {
+			p = make(map[SymbolKey]int)
+			ix.postings[term] = p
+		}
+		p[k]++
+		ix.lengths[k]++
+		ix.totalLength++
+	}
+}
+
+// Scores returns the BM25 score of the definitions matching any term of the
+// query.
+func (ix *LexicalIndex) Scores(query string) map[SymbolKey]float64 {
+	scores := make(map[SymbolKey]float64)
+	if len(ix.lengths) == 0 {
+		return scores
+	}
+	n := float64(len(ix.lengths))
+	avgLength := float64(ix.totalLength) / n
+
+	seen := make(map[string]struct{})
+	for _, term := range lexicalTerms(query) {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+
+		p := ix.postings[term]
+		if len(p) == 0 {
+			continue
+		}
+		df := float64(len(p))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for k, tf := range p {
+			f := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(ix.lengths[k])/avgLength
+			scores[k] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	return scores
+}
+
+// Search returns the definitions matching the query by decreasing score, at
+// most limit of them, or all when limit is zero or less.
+func (ix *LexicalIndex) Search(query string, limit int) []LexicalMatch {
+	var matches []LexicalMatch
+	for k, s := range ix.Scores(query) {
+		matches = append(matches, LexicalMatch{Symbol: k, Score: s})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Symbol.FileName != matches[j].Symbol.FileName {
+			return matches[i].Symbol.FileName < matches[j].Symbol.FileName
+		}
+		return matches[i].Symbol.Symbol < matches[j].Symbol.Symbol
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// lexicalTerms splits text into lowercase terms, splitting identifiers into
+// their words and dropping stop words and single characters.
+func lexicalTerms(text string) []string {
+	var terms []string
+	for _, token := range identPattern.FindAllString(text, -1) {
+		for _, w := range identWords(token) {
+			if len(w) < 2 {
+				continue
+			}
+			if _, ok := stopWords[w]; ok {
+				continue
+			}
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// buildLexicalIndex indexes each definition by the words of its name, counted
+// twice as the strongest signal, of its doc comment, and of its file path.
+func (r *RepoMap) buildLexicalIndex(allTags []Tag) *LexicalIndex {
+	ix := NewLexicalIndex()
+
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		lines := r.sourceLines(t.FilePath)
+
+		rel := r.GetRelFname(t.FilePath)
+		k := SymbolKey{FileName: rel, Symbol: t.QualifiedName()}
+		p := filepath.ToSlash(rel)
+		ix.Add(k, strings.Join([]string{
+			t.Name,
+			t.Name,
+			docComment(lines, t.Line),
+			strings.TrimSuffix(p, path.Ext(p)),
+		}, " "))
+	}
+	return ix
+}
+
+// cachedLines are the lines of a source file, and the modification time and
+// size of the file they were read at.
+type cachedLines struct {
+	modTime time.Time
+	size    int64
+	lines   []string
+}
+
+// sourceLines returns the lines of fname, nil when it cannot be read. They
+// are cached across maps until the file changes.
+func (r *RepoMap) sourceLines(fname string) []string {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return nil
+	}
+	if c, ok := r.sources[fname]; ok && c.modTime.Equal(info.ModTime()) && c.size == info.Size() {
+		return c.lines
+	}
+
+	var lines []string
+	if code, err := os.ReadFile(fname); err == nil {
+		lines = strings.Split(string(code), "\n")
+	}
+	if r.sources == nil {
+		r.sources = make(map[string]cachedLines)
+	}
+	r.sources[fname] = cachedLines{modTime: info.ModTime(), size: info.Size(), lines: lines}
+	return lines
+}
+
+// docComment returns the comment lines right above the 0-based line,
+// skipping annotations and decorators, and the first line of a Python
+// docstring right below it.
+func docComment(lines []string, line int) string {
+	if line >= len(lines) {
+		return ""
+	}
+
+	var doc []string
+	for i := line - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(l, "@") {
+			continue
+		}
+		if !isCommentLine(l) {
+			break
+		}
+		doc = append(doc, l)
+	}
+	if line+1 < len(lines) {
+		l := strings.TrimSpace(lines[line+1])
+		if strings.HasPrefix(l, `"""`) || strings.HasPrefix(l, `'''`) {
+			doc = append(doc, l)
+		}
+	}
+	return strings.Join(doc, " ")
+}
+
+// isCommentLine reports whether a trimmed line is a comment in a common
+// language.
+func isCommentLine(l string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", ";"} {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchDefinitions returns the definitions of fnames matching a
+// natural-language query by BM25 relevance, at most limit of them, or all
+// when limit is zero or less.
+func (r *RepoMap) SearchDefinitions(fnames []string, query string, limit int) []LexicalMatch {
+	return r.buildLexicalIndex(r.getTagsFromFiles(fnames, commonWords)).Search(query, limit)
+}
+
+// GenerateWithQuery generates the repo map with definitions ordered by a
+// blend of their graph rank and their relevance to a natural-language query,
+// see RankingConfig.QueryWeight.
+func (r *RepoMap) GenerateWithQuery(
+	chatFiles, otherFiles []string,
+	query string,
+	mentionedFnames, mentionedIdents map[string]bool,
+) string {
+	r.query = query
+	defer func() { r.query = "" }()
+
+	return r.Generate(chatFiles, otherFiles, mentionedFnames, mentionedIdents)
+}
+
+// blendQuery blends the rank of the definitions, normalized by the highest,
+// with their relevance to the query, if any, see queryRelevance, also
+// returned. Definitions relevant to the query but unranked are added.
+func (r *RepoMap) blendQuery(ranked []DefRank, allTags []Tag) ([]DefRank, map[SymbolKey]float64) {
+	if strings.TrimSpace(r.query) == "" {
+		return ranked, nil
+	}
+	relevance := r.queryRelevance(allTags)
+	if len(relevance) == 0 {
+		return ranked, relevance
+	}
+
+	w := r.ranking().QueryWeight
+	maxRank := 0.0
+	for _, dr := range ranked {
+		maxRank = math.Max(maxRank, dr.rank)
+	}
+
+	seen := make(map[SymbolKey]struct{}, len(ranked))
+	for i, dr := range ranked {
+		k := SymbolKey{FileName: dr.fname, Symbol: dr.symbol}
+		seen[k] = struct{}{}
+		rank := 0.0
+		if maxRank > 0 {
+			rank = dr.rank / maxRank
+		}
+		ranked[i].rank = (1-w)*rank + w*relevance[k]
+	}
+	for k, rel := range relevance {
+		if _, ok := seen[k]; !ok && w > 0 {
+			ranked = append(ranked, DefRank{fname: k.FileName, symbol: k.Symbol, rank: w * rel})
+		}
+	}
+	return ranked, relevance
+}
+
+// queryRelevance returns the relevance of the definitions to the query,
+// between 0 and 1: their BM25 score normalized by the highest, blended with
+// their normalized semantic similarity when an embedder is set, see
+// RankingConfig.SemanticWeight.
+func (r *RepoMap) queryRelevance(allTags []Tag) map[SymbolKey]float64 {
+	lexical := normalizeScores(r.buildLexicalIndex(allTags).Scores(r.query))
+	semantic := normalizeScores(r.semanticScores(allTags))
+	if len(semantic) == 0 {
+		return lexical
+	}
+
+	w := r.ranking().SemanticWeight
+	relevance := make(map[SymbolKey]float64, len(semantic))
+	for k, s := range lexical {
+		relevance[k] += (1 - w) * s
+	}
+	for k, s := range semantic {
+		relevance[k] += w * s
+	}
+	return relevance
+}
+
+// normalizeScores divides the positive scores by the highest, dropping the
+// others.
+func normalizeScores(scores map[SymbolKey]float64) map[SymbolKey]float64 {
+	maxScore := 0.0
+	for _, s := range scores {
+		maxScore = math.Max(maxScore, s)
+	}
+	normalized := make(map[SymbolKey]float64, len(scores))
+	for k, s := range scores {
+		if s > 0 {
+			normalized[k] = s / maxScore
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,110 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexicalTerms(t *testing.T) {
+	assert.Equal(t, []string{"parse", "http", "config", "file"}, lexicalTerms("How to parseHTTPConfig from a file?"))
+	assert.Equal(t, []string{"internal", "user", "store", "go"}, lexicalTerms("internal/user_store.go"))
+	assert.Empty(t, lexicalTerms("what is it"))
+}
+
+func TestDocComment(t *testing.T) {
+	lines := []string{
+		"package app",
+		"",
+		"// renewToken refreshes the session",
+		"// before it expires.",
+		"@deprecated",
+		"func renewToken() {}",
+		"def rotate_keys():",
+		`    """Rotate the signing keys."""`,
+	}
+	assert.Equal(t, "// before it expires. // renewToken refreshes the session", docComment(lines, 5))
+	assert.Equal(t, `"""Rotate the signing keys."""`, docComment(lines, 6))
+	assert.Equal(t, "", docComment(lines, 0))
+	assert.Equal(t, "", docComment(nil, 3))
+}
+
+// TestSourceLines verifies the lines of a file are read once until it
+// changes.
+func TestSourceLines(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{"app.go": "package app\n"})
+
+	r := NewRepoMap(root, nil)
+	assert.Equal(t, []string{"package app", ""}, r.sourceLines(fnames[0]))
+
+	// A cached entry is returned while the file is unchanged
+	c := r.sources[fnames[0]]
+	c.lines = []string{"cached"}
+	r.sources[fnames[0]] = c
+	assert.Equal(t, []string{"cached"}, r.sourceLines(fnames[0]))
+
+	writeTestFiles(t, root, map[string]string{"app.go": "package app\n\nfunc run() {}\n"})
+	assert.Equal(t, []string{"package app", "", "func run() {}", ""}, r.sourceLines(fnames[0]))
+	assert.Nil(t, r.sourceLines(filepath.Join(root, "missing.go")))
+}
+
+// TestLexicalIndex verifies BM25 ranks rare terms and short definitions
+// higher.
+func TestLexicalIndex(t *testing.T) {
+	ix := NewLexicalIndex()
+	invoice := SymbolKey{FileName: "billing.go", Symbol: "computeInvoice"}
+	refund := SymbolKey{FileName: "billing.go", Symbol: "issueRefund"}
+	report := SymbolKey{FileName: "report.go", Symbol: "invoiceReport"}
+	ix.Add(invoice, "compute invoice total tax")
+	ix.Add(refund, "issue refund total")
+	ix.Add(report, "invoice report total pages layout fonts margins")
+
+	scores := ix.Scores("invoice total")
+	assert.Len(t, scores, 3)
+	assert.Greater(t, scores[invoice], scores[report])
+	assert.Greater(t, scores[report], scores[refund])
+
+	assert.Equal(t, []LexicalMatch{{Symbol: invoice, Score: scores[invoice]}}, ix.Search("invoice total", 1))
+	assert.Empty(t, ix.Search("unrelated", 0))
+	assert.Empty(t, NewLexicalIndex().Scores("invoice"))
+
+	// Terms added again to a definition count towards it
+	ix.Add(refund, "invoice")
+	assert.Len(t, ix.Scores("invoice"), 3)
+	assert.Equal(t, 1, ix.postings["invoice"][refund])
+	assert.Equal(t, 4, ix.lengths[refund])
+}
+
+// TestGenerateWithQuery verifies definitions relevant to the query are
+// ranked ahead of more referenced ones.
+func TestGenerateWithQuery(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":          "package app\n\nfunc run() {\n\tformatOutput()\n\tformatOutput()\n\tvalidateSignature()\n}\n",
+		"format.go":        "package app\n\nfunc formatOutput() {}\n",
+		"crypto/verify.go": "package crypto\n\n// validateSignature checks the webhook signature.\nfunc validateSignature() {}\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	matches := r.SearchDefinitions(fnames, "where is the webhook signature checked", 0)
+	if assert.NotEmpty(t, matches) {
+		assert.Equal(t, SymbolKey{FileName: "crypto/verify.go", Symbol: "crypto.validateSignature"}, matches[0].Symbol)
+	}
+
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	ranked := r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "formatOutput", ranked[0].Name)
+
+	r.query = "webhook signature"
+	ranked = r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "validateSignature", ranked[0].Name)
+	ranked = r.getRankedTagsBySymbolRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Equal(t, "validateSignature", ranked[0].Name)
+
+	r.query = ""
+	out := r.GenerateWithQuery(nil, fnames, "webhook signature", nil, nil)
+	assert.Contains(t, out, "validateSignature")
+	assert.Empty(t, r.query)
+}
@@ -0,0 +1,95 @@
+package germ
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// localScope is the byte range of a @local.scope node along with the names
+// bound directly within it, and the start byte of their first definition.
+type localScope struct {
+	start, end uint
+	names      map[string]uint
+}
+
+// resolveLocals runs a locals query (tree-sitter's locals.scm convention) over
+// the parse tree and returns the start bytes of every @local.reference node that
+// resolves to a @local.definition in one of its enclosing @local.scope nodes,
+// defined before the reference.
+//
+// Definitions outside of any scope (eg. package or module level) are not
+// considered local, so references to them are left alone.
+func resolveLocals(q *sitter.Query, tree *sitter.Tree, sourceCode []byte) map[uint]struct{} {
+	type localName struct {
+		name       string
+		start, end uint
+	}
+
+	var (
+		scopes []*localScope
+		defs   []localName
+		refs   []localName
+	)
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	captures := qc.Captures(q, tree.RootNode(), sourceCode)
+	for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+		c := match.Captures[index]
+		n := localName{
+			name:  string(c.Node.Utf8Text(sourceCode)),
+			start: c.Node.StartByte(),
+			end:   c.Node.EndByte(),
+		}
+
+		switch q.CaptureNames()[c.Index] {
+		case "local.scope":
+			scopes = append(scopes, &localScope{start: n.start, end: n.end, names: map[string]uint{}})
+		case "local.definition":
+			defs = append(defs, n)
+		case "local.reference":
+			refs = append(refs, n)
+		}
+	}
+
+	// Bind each definition to its innermost enclosing scope
+	for _, d := range defs {
+		if s := innermostScope(scopes, d.start, d.end); s != nil {
+			if start, ok := s.names[d.name]; !ok || d.start < start {
+				s.names[d.name] = d.start
+			}
+		}
+	}
+
+	// A reference is local if any scope enclosing it binds the same name
+	// before it, so uses of an outer symbol ahead of a shadowing definition
+	// are kept
+	localRefs := make(map[uint]struct{})
+	for _, ref := range refs {
+		for _, s := range scopes {
+			if s.start > ref.start || ref.end > s.end {
+				continue
+			}
+			if start, ok := s.names[ref.name]; ok && start < ref.start {
+				localRefs[ref.start] = struct{}{}
+				break
+			}
+		}
+	}
+
+	return localRefs
+}
+
+// innermostScope returns the smallest scope containing [start, end), or nil.
+func innermostScope(scopes []*localScope, start, end uint) *localScope {
+	var best *localScope
+	for _, s := range scopes {
+		if s.start > start || end > s.end {
+			continue
+		}
+		if best == nil || s.end-s.start < best.end-best.start {
+			best = s
+		}
+	}
+	return best
+}
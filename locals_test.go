@@ -0,0 +1,89 @@
+package germ
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResolveLocals verifies references bound to local variables and
+// parameters are dropped while references to other symbols are kept.
+func TestResolveLocals(t *testing.T) {
+	tests := []struct {
+		name     string
+		fname    string
+		source   string
+		wantRefs []string
+		dropRefs []string
+	}{
+		{
+			name:  "Go",
+			fname: "main.go",
+			source: `package main
+
+var Global = func() {}
+
+func Run(callback func()) {
+	local := func() {}
+	local()
+	callback()
+	Global()
+	Dial()
+}
+`,
+			wantRefs: []string{"Global", "Dial"},
+			dropRefs: []string{"local", "callback"},
+		},
+		{
+			name:  "Go shadowing after use",
+			fname: "shadow.go",
+			source: `package main
+
+func Run() {
+	Connect()
+	Connect := func() {}
+	Connect()
+}
+`,
+			wantRefs: []string{"Connect"},
+		},
+		{
+			name:  "Python",
+			fname: "main.py",
+			source: `def run(callback, *handlers):
+    helper = make_helper()
+    helper()
+    callback()
+    for handler in handlers:
+        handler()
+    connect()
+`,
+			wantRefs: []string{"make_helper", "connect"},
+			dropRefs: []string{"helper", "callback", "handler"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), tt.fname)
+			if err := os.WriteFile(fname, []byte(tt.source), 0o644); err != nil {
+				t.Fatalf("Failed to write source: %v", err)
+			}
+
+			r := &RepoMap{}
+			tags, err := r.GetTagsRaw(fname, tt.fname, nil)
+			if err != nil {
+				t.Fatalf("Failed to get tags: %v", err)
+			}
+
+			for _, name := range tt.wantRefs {
+				assert.NotNil(t, findTag(tags, TagKindRef, name), "Expected reference to %s", name)
+			}
+			for _, name := range tt.dropRefs {
+				assert.Nil(t, findTag(tags, TagKindRef, name), "Expected local reference to %s to be dropped", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package germ
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Mention weights, from an exact mention down to a fuzzy match
+const (
+	mentionExact    = 1.0
+	mentionSuffix   = 0.9
+	mentionBasename = 0.8
+	mentionStyle    = 0.8
+	mentionPhrase   = 0.6
+	mentionFuzzy    = 0.5
+	// minMentionWeight is the weight from which GenerateWithMentions uses a
+	// mention
+	minMentionWeight = 0.5
+)
+
+var (
+	// identPattern matches identifiers, including kebab-case ones
+	identPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(?:-[A-Za-z0-9_]+)*`)
+	// wordPattern matches the words of a sentence
+	wordPattern = regexp.MustCompile(`[A-Za-z0-9]+`)
+)
+
+// Mentions are the files and identifiers mentioned in chat messages, with
+// weights between 0 and 1, 1 being an exact mention.
+type Mentions struct {
+	// Files maps mentioned files, relative to the root, to their weight
+	Files map[string]float64
+	// Idents maps mentioned identifiers, as defined, to their weight
+	Idents map[string]float64
+	// Lines maps files to the 0-based lines of interest of stack traces and
+	// diagnostics, see GetMentions
+	Lines map[string][]int
+}
+
+// ExtractMentions finds the known files and identifiers mentioned in messages.
+// Files, relative to the root, match by path, path suffix, or basename when
+// few files share it. Identifiers match exactly, in another naming style
+// (camelCase, snake_case, kebab-case), as a phrase of their words, or with a
+// typo. Each mention keeps its best weight across messages.
+func ExtractMentions(messages []string, files, idents []string) Mentions {
+	m := Mentions{
+		Files:  make(map[string]float64),
+		Idents: make(map[string]float64),
+		Lines:  make(map[string][]int),
+	}
+
+	byBasename := make(map[string][]string)
+	for _, f := range files {
+		f = filepath.ToSlash(f)
+		byBasename[path.Base(f)] = append(byBasename[path.Base(f)], f)
+	}
+
+	known := make(map[string]struct{}, len(idents))
+	byWords := make(map[string][]string)
+	for _, id := range idents {
+		known[id] = struct{}{}
+		w := strings.Join(identWords(id), " ")
+		byWords[w] = appendUnique(byWords[w], id)
+	}
+
+	for _, msg := range messages {
+		for _, word := range strings.Fields(msg) {
+			m.addFileMentions(pathWord(word), files, byBasename)
+		}
+
+		for _, token := range identPattern.FindAllString(msg, -1) {
+			m.addIdentMentions(token, known, byWords)
+		}
+
+		// Phrases of up to 4 words, eg. "parse config" for parseConfig
+		words := wordPattern.FindAllString(strings.ToLower(msg), -1)
+		for i := range words {
+			for n := 2; n <= 4 && i+n <= len(words); n++ {
+				for _, id := range byWords[strings.Join(words[i:i+n], " ")] {
+					m.addIdent(id, mentionPhrase)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// pathWord strips the punctuation and line suffix around a path in a
+// sentence, eg. "(src/app.go:12)," becomes src/app.go.
+func pathWord(word string) string {
+	word = strings.Trim(word, "\"'`()[]{}<>,;!?")
+	word = strings.TrimRight(word, ".:")
+	if i := strings.Index(word, ":"); i > 0 {
+		word = word[:i]
+	}
+	return filepath.ToSlash(strings.TrimPrefix(word, "./"))
+}
+
+// addFileMentions records the known files a word of a message designates.
+func (m Mentions) addFileMentions(word string, files []string, byBasename map[string][]string) {
+	if word == "" {
+		return
+	}
+	if strings.Contains(word, "/") {
+		for _, f := range files {
+			f = filepath.ToSlash(f)
+			switch {
+			case word == f || strings.HasSuffix(word, "/"+f):
+				m.addFile(f, mentionExact)
+			case strings.HasSuffix(f, "/"+word):
+				m.addFile(f, mentionSuffix)
+			}
+		}
+		return
+	}
+	// A basename shared by several files is a weaker mention of each
+	same := byBasename[word]
+	for _, f := range same {
+		if f == word {
+			m.addFile(f, mentionExact)
+		} else {
+			m.addFile(f, mentionBasename/float64(len(same)))
+		}
+	}
+}
+
+// addIdentMentions records the known identifiers a token of a message
+// designates.
+func (m Mentions) addIdentMentions(token string, known map[string]struct{}, byWords map[string][]string) {
+	if len(token) <= 2 {
+		return
+	}
+	if _, ok := known[token]; ok {
+		m.addIdent(token, mentionExact)
+	}
+
+	words := strings.Join(identWords(token), " ")
+	for _, id := range byWords[words] {
+		if id != token {
+			m.addIdent(id, mentionStyle)
+		}
+	}
+
+	// Typos, on identifiers long enough not to match by accident
+	squashed := strings.ReplaceAll(words, " ", "")
+	if len(squashed) < 6 {
+		return
+	}
+	for w, ids := range byWords {
+		other := strings.ReplaceAll(w, " ", "")
+		if d := len(other) - len(squashed); d < -1 || d > 1 {
+			continue
+		}
+		if other != squashed && editDistance(other, squashed) == 1 {
+			for _, id := range ids {
+				m.addIdent(id, mentionFuzzy)
+			}
+		}
+	}
+}
+
+// addFile records a file mention, keeping its best weight.
+func (m Mentions) addFile(f string, w float64) {
+	if w > m.Files[f] {
+		m.Files[f] = w
+	}
+}
+
+// addIdent records an identifier mention, keeping its best weight.
+func (m Mentions) addIdent(id string, w float64) {
+	if w > m.Idents[id] {
+		m.Idents[id] = w
+	}
+}
+
+// FnameSet returns the files mentioned with at least the given weight.
+func (m Mentions) FnameSet(minWeight float64) map[string]bool {
+	return weightSet(m.Files, minWeight)
+}
+
+// IdentSet returns the identifiers mentioned with at least the given weight.
+func (m Mentions) IdentSet(minWeight float64) map[string]bool {
+	return weightSet(m.Idents, minWeight)
+}
+
+// weightSet returns the keys of weights with at least minWeight.
+func weightSet(weights map[string]float64, minWeight float64) map[string]bool {
+	set := make(map[string]bool)
+	for k, w := range weights {
+		if w >= minWeight {
+			set[k] = true
+		}
+	}
+	return set
+}
+
+// identWords splits an identifier into its lowercase words, across
+// camelCase, PascalCase, snake_case and kebab-case, eg. parseHTTPConfig
+// becomes parse, http and config.
+func identWords(id string) []string {
+	var words []string
+	runes := []rune(id)
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			words = append(words, strings.ToLower(string(runes[start:end])))
+		}
+		start = -1
+	}
+	for i, c := range runes {
+		if c == '_' || c == '-' || !(unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			flush(i)
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// fooBar, or the last capital of an acronym: HTTPConfig
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush(i)
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	flush(len(runes))
+	return words
+}
+
+// editDistance returns the optimal string alignment distance between two
+// strings: the Levenshtein distance where swapping adjacent characters, a
+// common typo, counts as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// GetMentions extracts the files among fnames, and the identifiers they
+// define, mentioned in messages, including the files, functions and lines of
+// the stack traces and diagnostics they contain.
+func (r *RepoMap) GetMentions(messages []string, fnames []string) Mentions {
+	return r.getMentions(messages, fnames, r.getTagsFromFiles(fnames, commonWords))
+}
+
+// getMentions is GetMentions with the tags of the files already parsed.
+func (r *RepoMap) getMentions(messages []string, fnames []string, allTags []Tag) Mentions {
+	files := make([]string, 0, len(fnames))
+	for _, f := range fnames {
+		files = append(files, r.GetRelFname(f))
+	}
+
+	var idents []string
+	seen := make(map[string]struct{})
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		if _, ok := seen[t.Name]; !ok {
+			seen[t.Name] = struct{}{}
+			idents = append(idents, t.Name)
+		}
+	}
+
+	m := ExtractMentions(messages, files, idents)
+	for _, msg := range messages {
+		m.addFrames(r.GetFrames(msg, fnames), seen)
+	}
+	return m
+}
+
+// GenerateWithMentions generates the repo map for the files and identifiers
+// mentioned in the chat messages, see GetMentions, ignoring weak mentions.
+// The lines of the stack traces and diagnostics they contain are shown.
+func (r *RepoMap) GenerateWithMentions(chatFiles, otherFiles []string, messages ...string) string {
+	fnames := uniqueElements(chatFiles, otherFiles)
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	m := r.getMentions(messages, fnames, allTags)
+
+	// The map is generated from the tags the mentions were extracted from
+	r.linesOfInterest, r.parsedTags = m.Lines, allTags
+	defer func() { r.linesOfInterest, r.parsedTags = nil, nil }()
+
+	return r.Generate(chatFiles, otherFiles, m.FnameSet(minMentionWeight), m.IdentSet(minMentionWeight))
+}
@@ -0,0 +1,121 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentWords(t *testing.T) {
+	tests := map[string][]string{
+		"parseConfig":     {"parse", "config"},
+		"ParseHTTPConfig": {"parse", "http", "config"},
+		"load_user_data":  {"load", "user", "data"},
+		"render-widget":   {"render", "widget"},
+		"__init__":        {"init"},
+		"Base64Encoder":   {"base64", "encoder"},
+	}
+	for id, want := range tests {
+		assert.Equal(t, want, identWords(id), id)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("render", "render"))
+	assert.Equal(t, 1, editDistance("render", "rendr"))
+	assert.Equal(t, 1, editDistance("render", "renders"))
+	assert.Equal(t, 1, editDistance("render", "rendre"))
+	assert.Equal(t, 3, editDistance("render", "random"))
+}
+
+// TestExtractMentions verifies files and identifiers are found in free-form
+// messages by path, basename, naming style, phrase and typo.
+func TestExtractMentions(t *testing.T) {
+	files := []string{
+		"api/handler.go",
+		"api/routes.go",
+		"internal/store/user_store.go",
+		"cmd/app/main.go",
+		"tools/main.go",
+		"README.md",
+	}
+	idents := []string{"RegisterRoutes", "load_user_profile", "renderDashboard", "SessionCache"}
+
+	m := ExtractMentions([]string{
+		"The crash is in (api/handler.go:42), see store/user_store.go and README.md.",
+		"Maybe main.go? RegisterRoutes calls LoadUserProfile, which should load user profile",
+		"and renderDashbaord uses session-cache",
+	}, files, idents)
+
+	assert.Equal(t, map[string]float64{
+		"api/handler.go":               mentionExact,
+		"internal/store/user_store.go": mentionSuffix,
+		"README.md":                    mentionExact,
+		"cmd/app/main.go":              mentionBasename / 2,
+		"tools/main.go":                mentionBasename / 2,
+	}, m.Files)
+
+	assert.Equal(t, map[string]float64{
+		"RegisterRoutes":    mentionExact,
+		"load_user_profile": mentionStyle,
+		"renderDashboard":   mentionFuzzy,
+		"SessionCache":      mentionStyle,
+	}, m.Idents)
+
+	// Ambiguous basenames fall below the threshold
+	assert.Equal(t, map[string]bool{
+		"api/handler.go":               true,
+		"internal/store/user_store.go": true,
+		"README.md":                    true,
+	}, m.FnameSet(minMentionWeight))
+
+	// Phrases of the words of an identifier
+	m = ExtractMentions([]string{"Where do we register routes?"}, nil, idents)
+	assert.Equal(t, map[string]float64{"RegisterRoutes": mentionPhrase}, m.Idents)
+}
+
+// TestGetMentions verifies mentions are extracted against the files and
+// definitions of the repository.
+func TestGetMentions(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"billing/invoice.go": "package billing\n\nfunc ComputeInvoiceTotal() {}\n",
+		"billing/tax.go":     "package billing\n\nfunc applyTaxRate() {}\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	m := r.GetMentions([]string{"why does compute_invoice_total ignore tax.go?"}, fnames)
+
+	assert.Equal(t, map[string]float64{filepath.ToSlash("billing/tax.go"): mentionBasename}, m.Files)
+	assert.Equal(t, map[string]float64{"ComputeInvoiceTotal": mentionStyle}, m.Idents)
+}
+
+// TestParsedTags verifies the map is ranked from the tags already parsed to
+// extract the mentions, when set, rather than parsing the files again.
+func TestParsedTags(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"app/main.go": "package app\n\nfunc run() {\n\tstaleTarget()\n}\n",
+		"app/util.go": "package app\n\nfunc staleTarget() {}\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	writeTestFiles(t, root, map[string]string{
+		"app/main.go": "package app\n\nfunc run() {\n\tfreshTarget()\n}\n",
+		"app/util.go": "package app\n\nfunc freshTarget() {}\n",
+	})
+
+	r.parsedTags = allTags
+	r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+	_, err := r.Explain("staleTarget")
+	assert.NoError(t, err)
+
+	r.parsedTags = nil
+	r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+	_, err = r.Explain("staleTarget")
+	assert.Error(t, err)
+	_, err = r.Explain("freshTarget")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,190 @@
+package germ
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testSuffixes and testPrefixes mark the stems of test files, eg.
+// server_test.go, server.spec.ts, ServerTest.java or test_server.py. The
+// camel case suffixes only mark a stem ending a word before them.
+var (
+	testSuffixes      = []string{"_test", ".test", ".spec"}
+	testCamelSuffixes = []string{"Tests", "Test"}
+	testPrefixes      = []string{"test_"}
+	testDirs          = map[string]struct{}{"test": {}, "tests": {}}
+)
+
+// WithProximityWeight personalizes the ranking towards the files near the
+// chat and mentioned files: in the same directory, a few directories away,
+// or their tests. The personalization of a file is multiplied by 1 + the
+// weight times its proximity, from 1 in the same directory down to 0 beyond
+// RankingConfig.ProximityMaxDistance. Without a ranker set, the map is then
+// ranked with PersonalizedPageRank; rankers ignoring the personalization, eg.
+// PageRank, ignore it. Zero disables it.
+func WithProximityWeight(value float64) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.proximityWeight = value
+	}
+}
+
+// proximityAnchorSet returns the relative paths of the chat and mentioned
+// files the proximity is measured from, or nil when disabled.
+func (r *RepoMap) proximityAnchorSet(chatFnames []string, mentionedFnames map[string]bool) []string {
+	if r.proximityWeight <= 0 {
+		return nil
+	}
+	var anchors []string
+	for _, f := range chatFnames {
+		anchors = appendUnique(anchors, filepath.ToSlash(r.GetRelFname(f)))
+	}
+	for f := range mentionedFnames {
+		anchors = appendUnique(anchors, filepath.ToSlash(f))
+	}
+	return anchors
+}
+
+// proximityBoost returns the personalization multiplier of a file relative
+// to the root: 1 + the proximity weight times its proximity to the closest
+// anchor, see WithProximityWeight.
+func (r *RepoMap) proximityBoost(rel string) float64 {
+	if r.proximityWeight <= 0 || len(r.proximityAnchors) == 0 {
+		return 1
+	}
+	maxDistance := r.ranking().ProximityMaxDistance
+	rel = filepath.ToSlash(rel)
+
+	best := 0.0
+	for _, a := range r.proximityAnchors {
+		best = max(best, proximity(dirDistance(a, rel), maxDistance))
+		if isTestPair(a, rel, maxDistance) {
+			best = 1
+		}
+	}
+	return 1 + r.proximityWeight*best
+}
+
+// proximity turns a directory distance into a proximity, 1 for the same
+// directory decreasing linearly to 0 beyond maxDistance.
+func proximity(distance, maxDistance int) float64 {
+	if distance > maxDistance {
+		return 0
+	}
+	return 1 - float64(distance)/float64(maxDistance+1)
+}
+
+// dirDistance returns the number of directories to walk up and down from the
+// directory of one slash separated file to the directory of the other.
+func dirDistance(a, b string) int {
+	split := func(p string) []string {
+		dir := path.Dir(p)
+		if dir == "." {
+			return nil
+		}
+		return strings.Split(dir, "/")
+	}
+	da, db := split(a), split(b)
+
+	common := 0
+	for common < len(da) && common < len(db) && da[common] == db[common] {
+		common++
+	}
+	return len(da) + len(db) - 2*common
+}
+
+// isTestPair reports whether one file is the test of the other, by their
+// stems without test markers, eg. src/app.ts and tests/app.spec.ts, so tests
+// kept in a separate tree stay close to their subject. The files must be at
+// most maxDistance directories apart, or the test in a test tree mirroring
+// the subject's directory (see mirrorsDir).
+func isTestPair(a, b string, maxDistance int) bool {
+	stemA, testA := testStem(a)
+	stemB, testB := testStem(b)
+	if testA == testB || stemA != stemB || stemA == "" {
+		return false
+	}
+	if dirDistance(a, b) <= maxDistance {
+		return true
+	}
+	if testB {
+		a, b = b, a
+	}
+	return mirrorsDir(path.Dir(a), path.Dir(b), maxDistance)
+}
+
+// mirrorsDir reports whether a test directory, eg. src/test/java, split
+// around its test or tests element mirrors a subject directory, eg.
+// src/main/java: the subject directory starts with what precedes the test
+// element, ends with what follows it, and has at most maxDistance other
+// directories in between.
+func mirrorsDir(testDir, dir string, maxDistance int) bool {
+	split := func(p string) []string {
+		if p == "." {
+			return nil
+		}
+		return strings.Split(p, "/")
+	}
+	td, d := split(testDir), split(dir)
+	for i, e := range td {
+		if _, ok := testDirs[e]; !ok {
+			continue
+		}
+		prefix, suffix := td[:i], td[i+1:]
+		between := len(d) - len(prefix) - len(suffix)
+		if between < 0 || between > maxDistance {
+			continue
+		}
+		if equalPath(prefix, d[:len(prefix)]) && equalPath(suffix, d[len(d)-len(suffix):]) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalPath reports whether two lists of path elements are equal.
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// testStem returns the base name of a file without extension and test
+// markers, and whether it had one.
+func testStem(p string) (string, bool) {
+	stem := path.Base(p)
+	stem = strings.TrimSuffix(stem, path.Ext(stem))
+	for _, m := range testSuffixes {
+		if s, ok := strings.CutSuffix(stem, m); ok && s != "" {
+			return s, true
+		}
+	}
+	for _, m := range testCamelSuffixes {
+		if s, ok := strings.CutSuffix(stem, m); ok && endsWord(s) {
+			return s, true
+		}
+	}
+	for _, m := range testPrefixes {
+		if s, ok := strings.CutPrefix(stem, m); ok && s != "" {
+			return s, true
+		}
+	}
+	return stem, false
+}
+
+// endsWord reports whether a name ends a word a camel case test marker may
+// follow, ie. its last rune is a letter or a digit: Server in ServerTest or
+// HTTP in HTTPTest, but not an empty name or one ending with a separator.
+// Since markers are matched case sensitively, Contest has none.
+func endsWord(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,147 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirDistance(t *testing.T) {
+	assert.Equal(t, 0, dirDistance("api/handler.go", "api/routes.go"))
+	assert.Equal(t, 0, dirDistance("main.go", "util.go"))
+	assert.Equal(t, 1, dirDistance("api/handler.go", "api/v2/routes.go"))
+	assert.Equal(t, 2, dirDistance("api/v1/a.go", "api/v2/b.go"))
+	assert.Equal(t, 3, dirDistance("main.go", "internal/store/sql/db.go"))
+}
+
+func TestProximity(t *testing.T) {
+	assert.Equal(t, 1.0, proximity(0, 2))
+	assert.InDelta(t, 2.0/3, proximity(1, 2), 1e-9)
+	assert.InDelta(t, 1.0/3, proximity(2, 2), 1e-9)
+	assert.Equal(t, 0.0, proximity(3, 2))
+}
+
+func TestIsTestPair(t *testing.T) {
+	assert.True(t, isTestPair("srv/server.go", "srv/server_test.go", 2))
+	assert.True(t, isTestPair("src/app.ts", "tests/app.spec.ts", 2))
+	assert.True(t, isTestPair("pkg/parser.py", "tests/test_parser.py", 2))
+	assert.True(t, isTestPair("src/main/java/Ledger.java", "src/test/java/LedgerTest.java", 2))
+	assert.True(t, isTestPair("srv/Server.java", "srv/ServerTests.java", 2))
+	assert.False(t, isTestPair("srv/server.go", "srv/client_test.go", 2))
+	assert.False(t, isTestPair("a/server.go", "b/server.go", 2))
+	assert.False(t, isTestPair("a_test.go", "b/a_test.go", 2))
+	assert.False(t, isTestPair("srv/Con.java", "srv/Contest.java", 2))
+	assert.False(t, isTestPair("srv/Con.java", "srv/Con_Test.java", 2))
+
+	// Beyond the distance, only tests in a mirrored test tree pair
+	assert.True(t, isTestPair("src/main/java/com/acme/Ledger.java", "src/test/java/com/acme/LedgerTest.java", 2))
+	assert.True(t, isTestPair("tests/api/test_handler.py", "app/api/handler.py", 1))
+	assert.False(t, isTestPair("vendor/lib/x/util.go", "internal/util_test.go", 2))
+	assert.False(t, isTestPair("lib/deep/x/server.py", "tests/test_server.py", 2))
+	assert.False(t, isTestPair("src/main/java/Ledger.java", "src/test/kotlin/LedgerTest.java", 1))
+}
+
+func TestProximityBoost(t *testing.T) {
+	r := &RepoMap{root: "/repo"}
+	assert.Empty(t, r.proximityAnchorSet([]string{"/repo/api/handler.go"}, nil))
+	assert.Equal(t, 1.0, r.proximityBoost("api/routes.go"))
+
+	r.proximityWeight = 3
+	r.proximityAnchors = r.proximityAnchorSet([]string{"/repo/api/handler.go"}, map[string]bool{"db/store.go": true})
+	assert.ElementsMatch(t, []string{"api/handler.go", "db/store.go"}, r.proximityAnchors)
+
+	assert.Equal(t, 4.0, r.proximityBoost("api/routes.go"))
+	assert.Equal(t, 4.0, r.proximityBoost("db/migrate.go"))
+	assert.Equal(t, 3.0, r.proximityBoost("api/v2/routes.go"))
+	assert.Equal(t, 4.0, r.proximityBoost("tests/handler_test.go"))
+	assert.Equal(t, 1.0, r.proximityBoost("third_party/x/y/handler_test.go"))
+	assert.Equal(t, 1.0, r.proximityBoost("web/static/js/app.js"))
+
+	cfg := DefaultRankingConfig()
+	cfg.ProximityMaxDistance = 1
+	r.rankingConfig = &cfg
+	assert.Equal(t, 1.0, r.proximityBoost("cmd/tool/main.go"))
+}
+
+// TestProximityRanking verifies the definitions used near the chat files
+// rank ahead of ones used as often further away.
+func TestProximityRanking(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"app/main.go":          "package app\n\nfunc run() {}\n",
+		"app/worker.go":        "package app\n\nfunc work() {\n\talphaTarget()\n}\n",
+		"vendor/lib/plugin.go": "package lib\n\nfunc plug() {\n\tbetaTarget()\n}\n",
+		"shared/alpha.go":      "package shared\n\nfunc alphaTarget() {}\n",
+		"shared/beta.go":       "package shared\n\nfunc betaTarget() {}\n",
+	})
+	chat := []string{filepath.Join(root, "app/main.go")}
+
+	for _, symbolRanking := range []bool{false, true} {
+		for _, proximityWeight := range []float64{0, 5} {
+			r := NewRepoMap(root, nil, WithRanker(PersonalizedPageRank{}), WithSymbolRanking(symbolRanking), WithProximityWeight(proximityWeight))
+			r.GetRankedTagsMap(chat, fnames, 0, map[string]bool{}, map[string]bool{})
+
+			alpha, err := r.Explain("alphaTarget")
+			if !assert.NoError(t, err) {
+				return
+			}
+			beta, _ := r.Explain("betaTarget")
+			if proximityWeight == 0 {
+				assert.InDelta(t, alpha[0].Rank, beta[0].Rank, 1e-9)
+				continue
+			}
+			assert.Greater(t, alpha[0].Rank, beta[0].Rank, "symbol ranking: %v", symbolRanking)
+		}
+	}
+
+	r := NewRepoMap(root, nil, WithProximityWeight(5))
+	r.GetRankedTagsMap(chat, fnames, 0, map[string]bool{}, map[string]bool{})
+	e, _ := r.Explain("app/worker.go")
+	assert.Equal(t, []Multiplier{{Name: "directory proximity personalization", Value: 6}}, e[0].Multipliers)
+
+	// Rankers ignoring the personalization do not apply it
+	r = NewRepoMap(root, nil, WithRanker(PageRank{}), WithProximityWeight(5))
+	r.GetRankedTagsMap(chat, fnames, 0, map[string]bool{}, map[string]bool{})
+	e, _ = r.Explain("app/worker.go")
+	assert.Empty(t, e[0].Multipliers)
+}
+
+// TestGenerateProximity verifies the proximity reorders the map generated
+// with the default ranker.
+func TestGenerateProximity(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"app/main.go":          "package app\n\nfunc run() {}\n",
+		"app/worker.go":        "package app\n\nfunc work() {\n\tbetaTarget()\n}\n",
+		"vendor/lib/plugin.go": "package lib\n\nfunc plug() {\n\talphaTarget()\n}\n",
+		"shared/alpha.go":      "package shared\n\nfunc alphaTarget() {}\n",
+		"shared/beta.go":       "package shared\n\nfunc betaTarget() {}\n",
+	})
+	chat := []string{filepath.Join(root, "app/main.go")}
+	var other []string
+	for _, f := range fnames {
+		if f != chat[0] {
+			other = append(other, f)
+		}
+	}
+
+	explain := func(opts ...func(*RepoMap)) (Explanation, Explanation) {
+		r := NewRepoMap(root, nil, opts...)
+		assert.NotEmpty(t, r.Generate(chat, other, nil, nil))
+		alpha, err := r.Explain("alphaTarget")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		beta, _ := r.Explain("betaTarget")
+		return alpha[0], beta[0]
+	}
+
+	alpha, beta := explain()
+	assert.InDelta(t, alpha.Rank, beta.Rank, 1e-9)
+
+	// The definition used next to the chat file moves ahead
+	alpha, beta = explain(WithProximityWeight(5))
+	assert.Equal(t, 1, beta.Position)
+	assert.Equal(t, 2, alpha.Position)
+}
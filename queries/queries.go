@@ -5,6 +5,9 @@ import (
 	"fmt"
 )
 
+//go:embed tree-sitter-bash-tags.scm
+var bashTagQuery []byte
+
 //go:embed tree-sitter-c_sharp-tags.scm
 var cSharpTagQuery []byte
 
@@ -14,6 +17,9 @@ var cTagQuery []byte
 //go:embed tree-sitter-cpp-tags.scm
 var cppTagQuery []byte
 
+//go:embed tree-sitter-css-tags.scm
+var cssTagQuery []byte
+
 //go:embed tree-sitter-dart-tags.scm
 var dartTagQuery []byte
 
@@ -29,6 +35,9 @@ var elmTagQuery []byte
 //go:embed tree-sitter-go-tags.scm
 var goTagQuery []byte
 
+//go:embed tree-sitter-html-tags.scm
+var htmlTagQuery []byte
+
 //go:embed tree-sitter-java-tags.scm
 var javaTagQuery []byte
 
@@ -53,16 +62,80 @@ var rustTagQuery []byte
 //go:embed tree-sitter-typescript-tags.scm
 var typescriptTagQuery []byte
 
+//go:embed tree-sitter-c-imports.scm
+var cImportsQuery []byte
+
+//go:embed tree-sitter-cpp-imports.scm
+var cppImportsQuery []byte
+
+//go:embed tree-sitter-go-imports.scm
+var goImportsQuery []byte
+
+//go:embed tree-sitter-java-imports.scm
+var javaImportsQuery []byte
+
+//go:embed tree-sitter-javascript-imports.scm
+var javascriptImportsQuery []byte
+
+//go:embed tree-sitter-python-imports.scm
+var pythonImportsQuery []byte
+
+//go:embed tree-sitter-rust-imports.scm
+var rustImportsQuery []byte
+
+//go:embed tree-sitter-typescript-imports.scm
+var typescriptImportsQuery []byte
+
+//go:embed tree-sitter-cpp-relations.scm
+var cppRelationsQuery []byte
+
+//go:embed tree-sitter-go-relations.scm
+var goRelationsQuery []byte
+
+//go:embed tree-sitter-java-relations.scm
+var javaRelationsQuery []byte
+
+//go:embed tree-sitter-javascript-relations.scm
+var javascriptRelationsQuery []byte
+
+//go:embed tree-sitter-python-relations.scm
+var pythonRelationsQuery []byte
+
+//go:embed tree-sitter-rust-relations.scm
+var rustRelationsQuery []byte
+
+//go:embed tree-sitter-typescript-relations.scm
+var typescriptRelationsQuery []byte
+
+//go:embed tree-sitter-go-locals.scm
+var goLocalsQuery []byte
+
+//go:embed tree-sitter-javascript-locals.scm
+var javascriptLocalsQuery []byte
+
+//go:embed tree-sitter-python-locals.scm
+var pythonLocalsQuery []byte
+
+//go:embed tree-sitter-rust-locals.scm
+var rustLocalsQuery []byte
+
+//go:embed tree-sitter-typescript-locals.scm
+var typescriptLocalsQuery []byte
+
 // SitterLanguage is the language for the sitter queries
 type SitterLanguage string
 
 const (
+	// Bash is the language for Bash
+	Bash SitterLanguage = "bash"
 	// CSharp is the language for C#
 	CSharp SitterLanguage = "csharp"
 	// C is the language for C
 	C SitterLanguage = "c"
 	// Cpp is the language for C++
 	Cpp SitterLanguage = "cpp"
+	// CSS is the language for CSS
+	CSS SitterLanguage = "css"
 	// Dart is the language for Dart
 	Dart SitterLanguage = "dart"
 	// Elisp is the language for Elisp
@@ -73,6 +146,8 @@ const (
 	Elm SitterLanguage = "elm"
 	// Go is the language for Go
 	Go SitterLanguage = "go"
+	// HTML is the language for HTML
+	HTML SitterLanguage = "html"
 	// Java is the language for Java
 	Java SitterLanguage = "java"
 	// Javascript is the language for Javascript
@@ -93,14 +168,17 @@ const (
 
 // queries is a map of sitter queries for each language
 var queries = map[SitterLanguage][]byte{
+	Bash:       bashTagQuery,
 	CSharp:     cSharpTagQuery,
 	C:          cTagQuery,
 	Cpp:        cppTagQuery,
+	CSS:        cssTagQuery,
 	Dart:       dartTagQuery,
 	Elisp:      elispTagQuery,
 	Elixir:     elixirTagQuery,
 	Elm:        elmTagQuery,
 	Go:         goTagQuery,
+	HTML:       htmlTagQuery,
 	Java:       javaTagQuery,
 	Javascript: javascriptTagQuery,
 	Ocaml:      ocamlTagQuery,
@@ -120,3 +198,68 @@ func GetSitterQuery(language SitterLanguage) ([]byte, error) {
 	}
 	return query, nil
 }
+
+// locals is a map of sitter locals queries for the languages that have one.
+// They follow the tree-sitter locals.scm convention: @local.scope,
+// @local.definition and @local.reference.
+var locals = map[SitterLanguage][]byte{
+	Go:         goLocalsQuery,
+	Javascript: javascriptLocalsQuery,
+	Python:     pythonLocalsQuery,
+	Rust:       rustLocalsQuery,
+	Typescript: typescriptLocalsQuery,
+}
+
+// GetLocalsQuery returns the sitter locals query for the given language
+func GetLocalsQuery(language SitterLanguage) ([]byte, error) {
+	query, ok := locals[language]
+	if !ok {
+		return []byte{}, fmt.Errorf("locals query not supported")
+	}
+	return query, nil
+}
+
+// imports is a map of sitter queries extracting import, use, mod and include
+// directives. Each specifier is captured as @import.<kind>, eg. @import.from
+// or @import.include.
+var imports = map[SitterLanguage][]byte{
+	C:          cImportsQuery,
+	Cpp:        cppImportsQuery,
+	Go:         goImportsQuery,
+	Java:       javaImportsQuery,
+	Javascript: javascriptImportsQuery,
+	Python:     pythonImportsQuery,
+	Rust:       rustImportsQuery,
+	Typescript: typescriptImportsQuery,
+}
+
+// GetImportsQuery returns the sitter imports query for the given language
+func GetImportsQuery(language SitterLanguage) ([]byte, error) {
+	query, ok := imports[language]
+	if !ok {
+		return []byte{}, fmt.Errorf("imports query not supported")
+	}
+	return query, nil
+}
+
+// relations is a map of sitter queries extracting type relations. The subtype
+// name is captured as @name and each supertype name as @relation.<kind>, eg.
+// @relation.extends, @relation.implements or @relation.embeds.
+var relations = map[SitterLanguage][]byte{
+	Cpp:        cppRelationsQuery,
+	Go:         goRelationsQuery,
+	Java:       javaRelationsQuery,
+	Javascript: javascriptRelationsQuery,
+	Python:     pythonRelationsQuery,
+	Rust:       rustRelationsQuery,
+	Typescript: typescriptRelationsQuery,
+}
+
+// GetRelationsQuery returns the sitter relations query for the given language
+func GetRelationsQuery(language SitterLanguage) ([]byte, error) {
+	query, ok := relations[language]
+	if !ok {
+		return []byte{}, fmt.Errorf("relations query not supported")
+	}
+	return query, nil
+}
@@ -23,6 +23,24 @@ func TestGetSitterQuery(t *testing.T) {
 			wantQuery: goTagQuery,
 			wantErr:   false,
 		},
+		{
+			name:      "valid language Bash",
+			language:  Bash,
+			wantQuery: bashTagQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "valid language CSS",
+			language:  CSS,
+			wantQuery: cssTagQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "valid language HTML",
+			language:  HTML,
+			wantQuery: htmlTagQuery,
+			wantErr:   false,
+		},
 		{
 			name:      "invalid language",
 			language:  "invalid",
@@ -45,3 +63,126 @@ func TestGetSitterQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLocalsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		language  SitterLanguage
+		wantQuery []byte
+		wantErr   bool
+	}{
+		{
+			name:      "valid language Go",
+			language:  Go,
+			wantQuery: goLocalsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "valid language Python",
+			language:  Python,
+			wantQuery: pythonLocalsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "language without locals",
+			language:  CSS,
+			wantQuery: []byte{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery, err := GetLocalsQuery(tt.language)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetLocalsQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(gotQuery) != string(tt.wantQuery) {
+				t.Errorf("GetLocalsQuery() = %v, want %v", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestGetImportsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		language  SitterLanguage
+		wantQuery []byte
+		wantErr   bool
+	}{
+		{
+			name:      "valid language Go",
+			language:  Go,
+			wantQuery: goImportsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "valid language C",
+			language:  C,
+			wantQuery: cImportsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "language without imports",
+			language:  HTML,
+			wantQuery: []byte{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery, err := GetImportsQuery(tt.language)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetImportsQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(gotQuery) != string(tt.wantQuery) {
+				t.Errorf("GetImportsQuery() = %v, want %v", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestGetRelationsQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		language  SitterLanguage
+		wantQuery []byte
+		wantErr   bool
+	}{
+		{
+			name:      "valid language Go",
+			language:  Go,
+			wantQuery: goRelationsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "valid language Java",
+			language:  Java,
+			wantQuery: javaRelationsQuery,
+			wantErr:   false,
+		},
+		{
+			name:      "language without relations",
+			language:  HTML,
+			wantQuery: []byte{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQuery, err := GetRelationsQuery(tt.language)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRelationsQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(gotQuery) != string(tt.wantQuery) {
+				t.Errorf("GetRelationsQuery() = %v, want %v", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+(
+  (comment)* @doc
+  .
+  (function_definition
+    name: (word) @name.definition.function) @definition.function
+  (#strip! @doc "^#\\s*")
+  (#set-adjacent! @doc @definition.function)
+)
+
+(command
+  name: (command_name (word) @name.reference.call)) @reference.call
@@ -2,8 +2,16 @@
 
 (declaration type: (union_specifier name: (type_identifier) @name.definition.class)) @definition.class
 
+(function_definition declarator: (function_declarator declarator: (identifier) @name.definition.function)) @definition.function
+
+(function_definition declarator: (pointer_declarator declarator: (function_declarator declarator: (identifier) @name.definition.function))) @definition.function
+
 (function_declarator declarator: (identifier) @name.definition.function) @definition.function
 
 (type_definition declarator: (type_identifier) @name.definition.type) @definition.type
 
 (enum_specifier name: (type_identifier) @name.definition.type) @definition.type
+
+(call_expression function: (identifier) @name.reference.call) @reference.call
+
+(call_expression function: (field_expression field: (field_identifier) @name.reference.call)) @reference.call
@@ -0,0 +1,5 @@
+(preproc_include
+  path: (string_literal (string_content) @import.include))
+
+(preproc_include
+  path: (system_lib_string) @import.system)
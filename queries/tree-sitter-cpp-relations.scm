@@ -0,0 +1,8 @@
+(_
+  name: (type_identifier) @name
+  (base_class_clause
+    [
+      (type_identifier) @relation.extends
+      (qualified_identifier name: (type_identifier) @relation.extends)
+      (template_type name: (type_identifier) @relation.extends)
+    ]))
@@ -2,14 +2,32 @@
 
 (declaration type: (union_specifier name: (type_identifier) @name.definition.class)) @definition.class
 
+(function_definition declarator: (function_declarator declarator: (identifier) @name.definition.function)) @definition.function
+
+(function_definition declarator: (pointer_declarator declarator: (function_declarator declarator: (identifier) @name.definition.function))) @definition.function
+
 (function_declarator declarator: (identifier) @name.definition.function) @definition.function
 
 (function_declarator declarator: (field_identifier) @name.definition.function) @definition.function
 
-(function_declarator declarator: (qualified_identifier scope: (namespace_identifier) @scope name: (identifier) @name.definition.method)) @definition.method
+(function_definition declarator: (function_declarator declarator: (qualified_identifier name: (identifier) @name.definition.method))) @definition.method
+
+(function_definition declarator: (function_declarator declarator: (qualified_identifier name: (qualified_identifier name: (identifier) @name.definition.method)))) @definition.method
+
+(function_declarator declarator: (qualified_identifier name: (identifier) @name.definition.method)) @definition.method
+
+(function_declarator declarator: (qualified_identifier name: (qualified_identifier name: (identifier) @name.definition.method))) @definition.method
 
 (type_definition declarator: (type_identifier) @name.definition.type) @definition.type
 
 (enum_specifier name: (type_identifier) @name.definition.type) @definition.type
 
 (class_specifier name: (type_identifier) @name.definition.class) @definition.class
+
+(call_expression function: (identifier) @name.reference.call) @reference.call
+
+(call_expression function: (field_expression field: (field_identifier) @name.reference.call)) @reference.call
+
+(call_expression function: (qualified_identifier name: (identifier) @name.reference.call)) @reference.call
+
+(call_expression function: (qualified_identifier name: (qualified_identifier name: (identifier) @name.reference.call))) @reference.call
@@ -0,0 +1,28 @@
+(class_selector
+  (class_name) @name.definition.class) @definition.class
+
+(id_selector
+  (id_name) @name.definition.id) @definition.id
+
+(
+  (declaration
+    (property_name) @name.definition.property) @definition.property
+  (#match? @name.definition.property "^--")
+)
+
+(keyframes_statement
+  (keyframes_name) @name.definition.keyframes) @definition.keyframes
+
+(
+  (call_expression
+    (function_name) @_fn
+    (arguments (plain_value) @name.reference.property)) @reference.property
+  (#eq? @_fn "var")
+)
+
+(
+  (declaration
+    (property_name) @_prop
+    (plain_value) @name.reference.keyframes) @reference.keyframes
+  (#match? @_prop "^animation(-name)?$")
+)
@@ -0,0 +1,3 @@
+(import_spec
+  path: (interpreted_string_literal
+    (interpreted_string_literal_content) @import.import))
@@ -0,0 +1,21 @@
+(type_spec
+  name: (type_identifier) @name
+  type: (struct_type
+    (field_declaration_list
+      (field_declaration
+        !name
+        type: [
+          (type_identifier) @relation.embeds
+          (pointer_type (type_identifier) @relation.embeds)
+          (qualified_type name: (type_identifier) @relation.embeds)
+          (pointer_type (qualified_type name: (type_identifier) @relation.embeds))
+        ]))))
+
+(type_spec
+  name: (type_identifier) @name
+  type: (interface_type
+    (type_elem
+      [
+        (type_identifier) @relation.embeds
+        (qualified_type name: (type_identifier) @relation.embeds)
+      ])))
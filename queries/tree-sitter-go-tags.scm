@@ -16,6 +16,17 @@
   (#set-adjacent! @doc @definition.method)
 )
 
+(
+  (comment)* @doc
+  .
+  (type_declaration
+    (type_spec
+      name: (type_identifier) @name.definition.interface
+      type: (interface_type)) @definition.interface)
+  (#strip! @doc "^//\\s*")
+  (#set-adjacent! @doc @definition.interface)
+)
+
 (call_expression
   function: [
     (identifier) @name.reference.call
@@ -27,4 +38,52 @@
 (type_spec
   name: (type_identifier) @name.definition.type) @definition.type
 
+(source_file
+  (const_declaration
+    (const_spec
+      name: (identifier) @name.definition.constant) @definition.constant))
+
+(source_file
+  (var_declaration
+    (var_spec
+      name: (identifier) @name.definition.variable) @definition.variable))
+
+(source_file
+  (var_declaration
+    (var_spec_list
+      (var_spec
+        name: (identifier) @name.definition.variable) @definition.variable)))
+
+(field_declaration
+  name: (field_identifier) @name.definition.field) @definition.field
+
+(field_declaration
+  !name
+  type: [
+    (type_identifier) @name.reference.embed
+    (qualified_type name: (type_identifier) @name.reference.embed)
+    (pointer_type (type_identifier) @name.reference.embed)
+    (pointer_type (qualified_type name: (type_identifier) @name.reference.embed))
+  ]) @reference.embed
+
+(method_elem
+  name: (field_identifier) @name.definition.method) @definition.method
+
+(type_elem
+  [
+    (type_identifier) @name.reference.embed
+    (qualified_type name: (type_identifier) @name.reference.embed)
+  ]) @reference.embed
+
+(import_spec
+  name: (package_identifier) @name.reference.import) @reference.import
+
+(import_spec
+  !name
+  path: (interpreted_string_literal
+    (interpreted_string_literal_content) @name.reference.import)) @reference.import
+
+(selector_expression
+  field: (field_identifier) @name.reference.field) @reference.field
+
 (type_identifier) @name.reference.type @reference.type
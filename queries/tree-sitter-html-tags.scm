@@ -0,0 +1,25 @@
+(
+  (attribute
+    (attribute_name) @_attr
+    (quoted_attribute_value (attribute_value) @name.definition.id)) @definition.id
+  (#eq? @_attr "id")
+)
+
+(
+  (attribute
+    (attribute_name) @_attr
+    (quoted_attribute_value (attribute_value) @name.reference.class)) @reference.class
+  (#eq? @_attr "class")
+)
+
+(
+  (start_tag
+    (tag_name) @name.reference.element) @reference.element
+  (#match? @name.reference.element "-")
+)
+
+(
+  (self_closing_tag
+    (tag_name) @name.reference.element) @reference.element
+  (#match? @name.reference.element "-")
+)
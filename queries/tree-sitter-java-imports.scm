@@ -0,0 +1,15 @@
+(import_declaration
+  "static"
+  (scoped_identifier) @import.static_wildcard
+  (asterisk))
+
+(import_declaration
+  "static"
+  (scoped_identifier) @import.static .)
+
+(import_declaration
+  (scoped_identifier) @import.wildcard
+  (asterisk))
+
+(import_declaration
+  (scoped_identifier) @import.import .)
@@ -0,0 +1,34 @@
+(class_declaration
+  name: (identifier) @name
+  superclass: (superclass
+    [
+      (type_identifier) @relation.extends
+      (generic_type (type_identifier) @relation.extends)
+    ]))
+
+(class_declaration
+  name: (identifier) @name
+  interfaces: (super_interfaces
+    (type_list
+      [
+        (type_identifier) @relation.implements
+        (generic_type (type_identifier) @relation.implements)
+      ])))
+
+(enum_declaration
+  name: (identifier) @name
+  interfaces: (super_interfaces
+    (type_list
+      [
+        (type_identifier) @relation.implements
+        (generic_type (type_identifier) @relation.implements)
+      ])))
+
+(interface_declaration
+  name: (identifier) @name
+  (extends_interfaces
+    (type_list
+      [
+        (type_identifier) @relation.extends
+        (generic_type (type_identifier) @relation.extends)
+      ])))
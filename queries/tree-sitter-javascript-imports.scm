@@ -0,0 +1,16 @@
+(import_statement
+  source: (string (string_fragment) @import.import))
+
+(export_statement
+  source: (string (string_fragment) @import.import))
+
+(call_expression
+  function: (import)
+  arguments: (arguments . (string (string_fragment) @import.import)))
+
+(
+  (call_expression
+    function: (identifier) @_fn
+    arguments: (arguments . (string (string_fragment) @import.require)))
+  (#eq? @_fn "require")
+)
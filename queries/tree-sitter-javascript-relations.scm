@@ -0,0 +1,7 @@
+(_
+  name: (identifier) @name
+  (class_heritage
+    [
+      (identifier) @relation.extends
+      (member_expression property: (property_identifier) @relation.extends)
+    ]))
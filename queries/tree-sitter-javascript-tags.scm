@@ -8,6 +8,9 @@
   (#select-adjacent! @doc @definition.method)
 )
 
+(method_definition
+  name: (private_property_identifier) @name.definition.method) @definition.method
+
 (
   (comment)* @doc
   .
@@ -25,7 +28,7 @@
   (comment)* @doc
   .
   [
-    (function
+    (function_expression
       name: (identifier) @name.definition.function)
     (function_declaration
       name: (identifier) @name.definition.function)
@@ -44,7 +47,7 @@
   (lexical_declaration
     (variable_declarator
       name: (identifier) @name.definition.function
-      value: [(arrow_function) (function)]) @definition.function)
+      value: [(arrow_function) (function_expression)]) @definition.function)
   (#strip! @doc "^[\\s\\*/]+|^[\\s\\*/]$")
   (#select-adjacent! @doc @definition.function)
 )
@@ -55,7 +58,7 @@
   (variable_declaration
     (variable_declarator
       name: (identifier) @name.definition.function
-      value: [(arrow_function) (function)]) @definition.function)
+      value: [(arrow_function) (function_expression)]) @definition.function)
   (#strip! @doc "^[\\s\\*/]+|^[\\s\\*/]$")
   (#select-adjacent! @doc @definition.function)
 )
@@ -66,12 +69,12 @@
     (member_expression
       property: (property_identifier) @name.definition.function)
   ]
-  right: [(arrow_function) (function)]
+  right: [(arrow_function) (function_expression)]
 ) @definition.function
 
 (pair
   key: (property_identifier) @name.definition.function
-  value: [(arrow_function) (function)]) @definition.function
+  value: [(arrow_function) (function_expression)]) @definition.function
 
 (
   (call_expression
@@ -86,3 +89,20 @@
 
 (new_expression
   constructor: (_) @name.reference.class) @reference.class
+
+(
+  (jsx_attribute
+    (property_identifier) @_attr
+    (string (string_fragment) @name.reference.class)) @reference.class
+  (#eq? @_attr "className")
+)
+
+(
+  (call_expression
+    function: (member_expression
+      object: (identifier) @_obj
+      property: (property_identifier) @_fn)
+    arguments: (arguments . (string (string_fragment) @name.definition.element))) @definition.element
+  (#eq? @_obj "customElements")
+  (#eq? @_fn "define")
+)
@@ -0,0 +1,23 @@
+(import_statement
+  name: (dotted_name) @import.import)
+
+(import_statement
+  name: (aliased_import
+    name: (dotted_name) @import.import))
+
+(import_from_statement
+  module_name: [
+    (dotted_name)
+    (relative_import)
+  ] @import.from)
+
+(import_from_statement
+  module_name: [
+    (dotted_name)
+    (relative_import)
+  ] @module
+  name: [
+    (dotted_name) @import.member
+    (aliased_import
+      name: (dotted_name) @import.member)
+  ])
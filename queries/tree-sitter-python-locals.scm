@@ -0,0 +1,41 @@
+; Scopes
+;-------
+
+[
+  (function_definition)
+  (lambda)
+  (list_comprehension)
+  (dictionary_comprehension)
+  (set_comprehension)
+  (generator_expression)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameters (identifier) @local.definition)
+(lambda_parameters (identifier) @local.definition)
+(default_parameter name: (identifier) @local.definition)
+(typed_parameter (identifier) @local.definition)
+(typed_default_parameter name: (identifier) @local.definition)
+(list_splat_pattern (identifier) @local.definition)
+(dictionary_splat_pattern (identifier) @local.definition)
+
+(assignment
+  left: (identifier) @local.definition)
+
+(assignment
+  left: (pattern_list (identifier) @local.definition))
+
+(for_statement
+  left: (identifier) @local.definition)
+
+(for_in_clause
+  left: (identifier) @local.definition)
+
+(as_pattern_target (identifier) @local.definition)
+
+; References
+;-----------
+
+(identifier) @local.reference
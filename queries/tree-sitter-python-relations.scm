@@ -0,0 +1,7 @@
+(class_definition
+  name: (identifier) @name
+  superclasses: (argument_list
+    [
+      (identifier) @relation.extends
+      (attribute attribute: (identifier) @relation.extends)
+    ]))
@@ -0,0 +1,6 @@
+(mod_item
+  name: (identifier) @import.mod
+  !body)
+
+(use_declaration
+  argument: (_) @import.use)
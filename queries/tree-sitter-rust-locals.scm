@@ -0,0 +1,33 @@
+; Scopes
+;-------
+
+[
+  (function_item)
+  (closure_expression)
+  (block)
+] @local.scope
+
+; Definitions
+;------------
+
+(parameter
+  pattern: (identifier) @local.definition)
+
+(parameter
+  pattern: (tuple_pattern (identifier) @local.definition))
+
+(closure_parameters (identifier) @local.definition)
+
+(let_declaration
+  pattern: (identifier) @local.definition)
+
+(let_declaration
+  pattern: (tuple_pattern (identifier) @local.definition))
+
+(for_expression
+  pattern: (identifier) @local.definition)
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,20 @@
+(impl_item
+  trait: [
+    (type_identifier) @relation.implements
+    (scoped_type_identifier name: (type_identifier) @relation.implements)
+    (generic_type type: (type_identifier) @relation.implements)
+  ]
+  type: [
+    (type_identifier) @name
+    (generic_type type: (type_identifier) @name)
+    (scoped_type_identifier name: (type_identifier) @name)
+  ])
+
+(trait_item
+  name: (type_identifier) @name
+  bounds: (trait_bounds
+    [
+      (type_identifier) @relation.extends
+      (scoped_type_identifier name: (type_identifier) @relation.extends)
+      (generic_type type: (type_identifier) @relation.extends)
+    ]))
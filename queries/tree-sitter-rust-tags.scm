@@ -18,7 +18,7 @@
 
 (declaration_list
     (function_item
-        name: (identifier) @name.definition.method)) @definition.method
+        name: (identifier) @name.definition.method) @definition.method)
 
 ; function definitions
 
@@ -47,6 +47,10 @@
     function: (field_expression
         field: (field_identifier) @name.reference.call)) @reference.call
 
+(call_expression
+    function: (scoped_identifier
+        name: (identifier) @name.reference.call)) @reference.call
+
 (macro_invocation
     macro: (identifier) @name.reference.call) @reference.call
 
@@ -0,0 +1,29 @@
+; Scopes
+;-------
+
+[
+  (statement_block)
+  (function_expression)
+  (arrow_function)
+  (function_declaration)
+  (method_definition)
+] @local.scope
+
+; Definitions
+;------------
+
+(pattern/identifier) @local.definition
+
+(variable_declarator
+  name: (identifier) @local.definition)
+
+(required_parameter
+  pattern: (identifier) @local.definition)
+
+(optional_parameter
+  pattern: (identifier) @local.definition)
+
+; References
+;-----------
+
+(identifier) @local.reference
@@ -0,0 +1,27 @@
+(_
+  name: (type_identifier) @name
+  (class_heritage
+    (extends_clause
+      value: [
+        (identifier) @relation.extends
+        (member_expression property: (property_identifier) @relation.extends)
+      ])))
+
+(_
+  name: (type_identifier) @name
+  (class_heritage
+    (implements_clause
+      [
+        (type_identifier) @relation.implements
+        (generic_type name: (type_identifier) @relation.implements)
+        (nested_type_identifier name: (type_identifier) @relation.implements)
+      ])))
+
+(interface_declaration
+  name: (type_identifier) @name
+  (extends_type_clause
+    type: [
+      (type_identifier) @relation.extends
+      (generic_type name: (type_identifier) @relation.extends)
+      (nested_type_identifier name: (type_identifier) @relation.extends)
+    ]))
@@ -28,6 +28,9 @@
 (method_definition
   name: (property_identifier) @name.definition.method) @definition.method
 
+(method_definition
+  name: (private_property_identifier) @name.definition.method) @definition.method
+
 (class_declaration
   name: (type_identifier) @name.definition.class) @definition.class
 
@@ -0,0 +1,278 @@
+package germ
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/network"
+	"gonum.org/v1/gonum/graph/path"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+const (
+	// defaultDamping is the PageRank damping factor
+	defaultDamping = 0.85
+	// defaultTolerance is the convergence tolerance of iterative rankers
+	defaultTolerance = 1e-6
+	// maxRankIterations bounds the power iterations of PersonalizedPageRank
+	maxRankIterations = 1000
+)
+
+// Ranker scores the nodes of the reference graph. Edges point from the
+// referencing node to the referenced one and are weighted by the strength of
+// the references. Personalization weighs the nodes of interest, eg. the chat
+// files; rankers that cannot use it ignore it.
+type Ranker interface {
+	Rank(g graph.WeightedDirected, personalization map[int64]float64) map[int64]float64
+}
+
+// PageRank is the edge-weighted PageRank of gonum, without personalization.
+// Zero fields use a damping of 0.85 and a tolerance of 1e-6.
+type PageRank struct {
+	Damping   float64
+	Tolerance float64
+}
+
+// Rank implements Ranker.
+func (p PageRank) Rank(g graph.WeightedDirected, _ map[int64]float64) map[int64]float64 {
+	if g.Nodes().Len() == 0 {
+		return map[int64]float64{}
+	}
+	return network.PageRank(g, orDefault(p.Damping, defaultDamping), orDefault(p.Tolerance, defaultTolerance))
+}
+
+// PersonalizedPageRank is an edge-weighted PageRank whose random jumps, and
+// the rank of nodes without out edges, land on nodes in proportion to their
+// personalization, so nodes close to the chat files rank higher. Without
+// personalization it is a plain PageRank. Zero fields use a damping of 0.85
+// and a tolerance of 1e-6.
+type PersonalizedPageRank struct {
+	Damping   float64
+	Tolerance float64
+}
+
+// Rank implements Ranker.
+func (p PersonalizedPageRank) Rank(g graph.WeightedDirected, personalization map[int64]float64) map[int64]float64 {
+	damping := orDefault(p.Damping, defaultDamping)
+	tolerance := orDefault(p.Tolerance, defaultTolerance)
+
+	nodes := graph.NodesOf(g.Nodes())
+	n := len(nodes)
+	ranks := make(map[int64]float64, n)
+	if n == 0 {
+		return ranks
+	}
+
+	indexOf := make(map[int64]int, n)
+	for i, u := range nodes {
+		indexOf[u.ID()] = i
+	}
+
+	// Teleport vector, uniform without personalization
+	jump := make([]float64, n)
+	var total float64
+	for i, u := range nodes {
+		if w := personalization[u.ID()]; w > 0 {
+			jump[i] = w
+			total += w
+		}
+	}
+	for i := range jump {
+		if total > 0 {
+			jump[i] /= total
+		} else {
+			jump[i] = 1 / float64(n)
+		}
+	}
+
+	// Out edges, normalized by the total out weight of their source
+	type link struct {
+		to     int
+		weight float64
+	}
+	out := make([][]link, n)
+	for i, u := range nodes {
+		var sum float64
+		to := g.From(u.ID())
+		for to.Next() {
+			v := to.Node()
+			w, _ := g.Weight(u.ID(), v.ID())
+			if w <= 0 {
+				continue
+			}
+			out[i] = append(out[i], link{to: indexOf[v.ID()], weight: w})
+			sum += w
+		}
+		for j := range out[i] {
+			out[i][j].weight /= sum
+		}
+	}
+
+	rank := append([]float64(nil), jump...)
+	next := make([]float64, n)
+	for iter := 0; iter < maxRankIterations; iter++ {
+		var dangling float64
+		for i := range next {
+			next[i] = 0
+		}
+		for i, links := range out {
+			if len(links) == 0 {
+				dangling += rank[i]
+				continue
+			}
+			for _, l := range links {
+				next[l.to] += rank[i] * l.weight
+			}
+		}
+
+		var diff float64
+		for i := range next {
+			next[i] = damping*(next[i]+dangling*jump[i]) + (1-damping)*jump[i]
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < tolerance {
+			break
+		}
+	}
+
+	for i, u := range nodes {
+		ranks[u.ID()] = rank[i]
+	}
+	return ranks
+}
+
+// HITS ranks nodes by their Hyperlink-Induced Topic Search authority score:
+// nodes referenced by good hubs, themselves referencing good authorities,
+// rank highest. Edge weights are ignored. A zero Tolerance uses 1e-6.
+type HITS struct {
+	Tolerance float64
+}
+
+// Rank implements Ranker.
+func (h HITS) Rank(g graph.WeightedDirected, _ map[int64]float64) map[int64]float64 {
+	ranks := make(map[int64]float64)
+	if g.Nodes().Len() == 0 {
+		return ranks
+	}
+	for id, ha := range network.HITS(g, orDefault(h.Tolerance, defaultTolerance)) {
+		ranks[id] = ha.Authority
+	}
+	return ranks
+}
+
+// InDegree ranks nodes by the total weight of the references to them.
+type InDegree struct{}
+
+// Rank implements Ranker.
+func (InDegree) Rank(g graph.WeightedDirected, _ map[int64]float64) map[int64]float64 {
+	ranks := make(map[int64]float64)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		v := nodes.Node()
+		var sum float64
+		from := g.To(v.ID())
+		for from.Next() {
+			w, _ := g.Weight(from.Node().ID(), v.ID())
+			sum += w
+		}
+		ranks[v.ID()] = sum
+	}
+	return ranks
+}
+
+// Betweenness ranks nodes by their betweenness centrality: the number of
+// shortest reference paths going through them. Edge weights are ignored, as
+// they measure strength rather than distance.
+type Betweenness struct{}
+
+// Rank implements Ranker.
+func (Betweenness) Rank(g graph.WeightedDirected, _ map[int64]float64) map[int64]float64 {
+	ranks := make(map[int64]float64)
+	u := unweighted(g)
+	nodes := u.Nodes()
+	for nodes.Next() {
+		ranks[nodes.Node().ID()] = 0
+	}
+	// Betweenness omits nodes on no shortest path
+	for id, b := range network.Betweenness(u) {
+		ranks[id] = b
+	}
+	return ranks
+}
+
+// Closeness ranks nodes by their harmonic closeness centrality: the sum of
+// the inverse distances from the nodes referencing them, directly or not.
+// Unlike plain closeness it handles disconnected graphs. Edge weights are
+// ignored, as they measure strength rather than distance.
+type Closeness struct{}
+
+// Rank implements Ranker.
+func (Closeness) Rank(g graph.WeightedDirected, _ map[int64]float64) map[int64]float64 {
+	u := unweighted(g)
+	if u.Nodes().Len() == 0 {
+		return map[int64]float64{}
+	}
+	return network.Harmonic(u, path.DijkstraAllPaths(u))
+}
+
+// unweighted returns a copy of the topology of g, merging parallel edges.
+func unweighted(g graph.Directed) *simple.DirectedGraph {
+	u := simple.NewDirectedGraph()
+	nodes := g.Nodes()
+	for nodes.Next() {
+		u.AddNode(simple.Node(nodes.Node().ID()))
+	}
+	nodes.Reset()
+	for nodes.Next() {
+		from := nodes.Node().ID()
+		to := g.From(from)
+		for to.Next() {
+			if id := to.Node().ID(); id != from {
+				u.SetEdge(simple.Edge{F: simple.Node(from), T: simple.Node(id)})
+			}
+		}
+	}
+	return u
+}
+
+// orDefault returns value, or def when value is zero.
+func orDefault(value, def float64) float64 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// ranker returns the configured Ranker, PageRank by default or
+// PersonalizedPageRank when the directory proximity applies, with the damping
+// and tolerance of the ranking config where the ranker leaves them zero.
+func (r *RepoMap) ranker() Ranker {
+	cfg := r.ranking()
+	switch rk := r.rankerImpl.(type) {
+	case nil:
+		if r.proximityWeight > 0 && len(r.proximityAnchors) > 0 {
+			return PersonalizedPageRank{Damping: cfg.Damping, Tolerance: cfg.Tolerance}
+		}
+		return PageRank{Damping: cfg.Damping, Tolerance: cfg.Tolerance}
+	case PageRank:
+		return PageRank{Damping: orDefault(rk.Damping, cfg.Damping), Tolerance: orDefault(rk.Tolerance, cfg.Tolerance)}
+	case PersonalizedPageRank:
+		return PersonalizedPageRank{Damping: orDefault(rk.Damping, cfg.Damping), Tolerance: orDefault(rk.Tolerance, cfg.Tolerance)}
+	case HITS:
+		return HITS{Tolerance: orDefault(rk.Tolerance, cfg.Tolerance)}
+	default:
+		return rk
+	}
+}
+
+// personalized reports whether a ranker uses the personalization. Custom
+// rankers are assumed to.
+func personalized(rk Ranker) bool {
+	switch rk.(type) {
+	case PageRank, HITS, InDegree, Betweenness, Closeness:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,103 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// rankerTestGraph returns a -> c, b -> c (weight 3) and c -> d.
+func rankerTestGraph() *simple.WeightedDirectedGraph {
+	g := simple.NewWeightedDirectedGraph(0, 0)
+	for id := int64(0); id < 4; id++ {
+		g.AddNode(simple.Node(id))
+	}
+	g.SetWeightedEdge(g.NewWeightedEdge(simple.Node(0), simple.Node(2), 1))
+	g.SetWeightedEdge(g.NewWeightedEdge(simple.Node(1), simple.Node(2), 3))
+	g.SetWeightedEdge(g.NewWeightedEdge(simple.Node(2), simple.Node(3), 1))
+	return g
+}
+
+func TestRankers(t *testing.T) {
+	g := rankerTestGraph()
+
+	rankers := map[string]Ranker{
+		"pagerank":     PageRank{},
+		"personalized": PersonalizedPageRank{},
+		"hits":         HITS{},
+		"indegree":     InDegree{},
+		"betweenness":  Betweenness{},
+		"closeness":    Closeness{},
+	}
+	for name, ranker := range rankers {
+		ranks := ranker.Rank(g, nil)
+		assert.Len(t, ranks, 4, name)
+		assert.Greater(t, ranks[2], ranks[0], name)
+		assert.Greater(t, ranks[2], ranks[1], name)
+	}
+
+	empty := simple.NewWeightedDirectedGraph(0, 0)
+	for name, ranker := range rankers {
+		assert.Empty(t, ranker.Rank(empty, nil), name)
+	}
+}
+
+func TestInDegreeRanker(t *testing.T) {
+	ranks := InDegree{}.Rank(rankerTestGraph(), nil)
+	assert.Equal(t, map[int64]float64{0: 0, 1: 0, 2: 4, 3: 1}, ranks)
+}
+
+func TestBetweennessRanker(t *testing.T) {
+	ranks := Betweenness{}.Rank(rankerTestGraph(), nil)
+	// c lies on the paths from a and b to d
+	assert.Equal(t, map[int64]float64{0: 0, 1: 0, 2: 2, 3: 0}, ranks)
+}
+
+func TestClosenessRanker(t *testing.T) {
+	ranks := Closeness{}.Rank(rankerTestGraph(), nil)
+	assert.Equal(t, 0.0, ranks[0])
+	assert.Equal(t, 2.0, ranks[2])
+	assert.Equal(t, 2.0, ranks[3])
+}
+
+func TestPersonalizedPageRank(t *testing.T) {
+	g := rankerTestGraph()
+
+	// Without personalization, it matches gonum's PageRank
+	plain := PersonalizedPageRank{Tolerance: 1e-10}.Rank(g, nil)
+	pr := PageRank{Tolerance: 1e-10}.Rank(g, nil)
+	for id, rank := range pr {
+		assert.InDelta(t, rank, plain[id], 1e-4)
+	}
+
+	sum := 0.0
+	for _, rank := range plain {
+		sum += rank
+	}
+	assert.InDelta(t, 1.0, sum, 1e-6)
+
+	// Personalizing a lifts it above b, despite b's stronger reference
+	personal := PersonalizedPageRank{}.Rank(g, map[int64]float64{0: 100, 1: 1, 2: 1, 3: 1})
+	assert.Greater(t, personal[0], personal[1])
+	assert.Greater(t, personal[0], plain[0])
+}
+
+func TestWithRanker(t *testing.T) {
+	r := NewRepoMap(".", nil)
+	assert.Equal(t, PageRank{Damping: 0.85, Tolerance: 1e-6}, r.ranker())
+
+	r = NewRepoMap(".", nil, WithRanker(HITS{}))
+	assert.Equal(t, HITS{Tolerance: 1e-6}, r.ranker())
+
+	r = NewRepoMap(".", nil, WithRanker(InDegree{}))
+	assert.Equal(t, InDegree{}, r.ranker())
+	assert.False(t, personalized(r.ranker()))
+
+	// The directory proximity personalizes the default ranker
+	r = NewRepoMap(".", nil, WithProximityWeight(1))
+	assert.Equal(t, PageRank{Damping: 0.85, Tolerance: 1e-6}, r.ranker())
+	r.proximityAnchors = []string{"main.go"}
+	assert.Equal(t, PersonalizedPageRank{Damping: 0.85, Tolerance: 1e-6}, r.ranker())
+	assert.True(t, personalized(r.ranker()))
+}
@@ -0,0 +1,136 @@
+package germ
+
+import (
+	"math"
+	"strings"
+)
+
+// RankingConfig holds the weights and heuristics of the reference graph.
+// Start from DefaultRankingConfig: a zero weight disables its signal.
+type RankingConfig struct {
+	// MentionedIdentWeight multiplies references to identifiers mentioned in
+	// the chat
+	MentionedIdentWeight float64
+	// ExportedWeight multiplies references to public definitions, see
+	// VisibilityPublic
+	ExportedWeight float64
+	// RestrictedWeight multiplies references to protected and package
+	// private definitions
+	RestrictedWeight float64
+	// PrivateIdentWeight multiplies references to private definitions, or to
+	// identifiers starting with an underscore in languages without known
+	// visibility rules
+	PrivateIdentWeight float64
+	// ReferenceWeight turns the number of references to a symbol, multiplied
+	// by the identifier weights, into an edge weight, math.Sqrt by default so
+	// heavily used symbols do not drown everything else. Nil keeps the
+	// default.
+	ReferenceWeight func(count float64) float64
+	// Damping is the damping factor of the default PageRank rankers, and
+	// Tolerance the convergence tolerance of the default iterative rankers.
+	// Zero keeps their default.
+	Damping   float64
+	Tolerance float64
+	// ChatFileWeight multiplies the personalization of the chat files
+	ChatFileWeight float64
+	// RecencyWeight, ChurnWeight and AuthorshipWeight scale the git history
+	// boost of a file, see WithGitHistory: the weight of the references to a
+	// file is multiplied by 1 + the weighted sum of its recency, churn and
+	// authorship, each between 0 and 1
+	RecencyWeight    float64
+	ChurnWeight      float64
+	AuthorshipWeight float64
+	// CoChangeMinSupport and CoChangeMinConfidence are the number of shared
+	// commits, and their share of the commits of a file, above which two
+	// files committed together are related, see WithCoChangeWeight
+	CoChangeMinSupport    int
+	CoChangeMinConfidence float64
+	// QueryWeight is the share, between 0 and 1, of the relevance to the
+	// query in the order of definitions, see GenerateWithQuery, the rest being
+	// their normalized graph rank
+	QueryWeight float64
+	// SemanticWeight is the share, between 0 and 1, of the semantic
+	// similarity in the relevance to the query when an embedder is set, see
+	// WithEmbedder, the rest being the lexical relevance
+	SemanticWeight float64
+	// ProximityMaxDistance is the number of directories between two files
+	// beyond which they are not near, see WithProximityWeight
+	ProximityMaxDistance int
+}
+
+// DefaultRankingConfig returns the default ranking weights.
+func DefaultRankingConfig() RankingConfig {
+	return RankingConfig{
+		MentionedIdentWeight:  10,
+		ExportedWeight:        1,
+		RestrictedWeight:      0.5,
+		PrivateIdentWeight:    0.1,
+		ReferenceWeight:       math.Sqrt,
+		Damping:               defaultDamping,
+		Tolerance:             defaultTolerance,
+		ChatFileWeight:        100,
+		RecencyWeight:         1,
+		ChurnWeight:           1,
+		AuthorshipWeight:      1,
+		CoChangeMinSupport:    2,
+		CoChangeMinConfidence: 0.5,
+		QueryWeight:           0.5,
+		SemanticWeight:        0.5,
+		ProximityMaxDistance:  2,
+	}
+}
+
+// WithRankingConfig sets the weights of the reference graph. Start from
+// DefaultRankingConfig and change the weights to tune, since zero weights
+// disable their signal.
+func WithRankingConfig(value RankingConfig) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.rankingConfig = &value
+	}
+}
+
+// withDefaults returns the config with the settings that cannot be zero, a
+// nil ReferenceWeight and a zero Damping or Tolerance, set to their default.
+func (c RankingConfig) withDefaults() RankingConfig {
+	def := DefaultRankingConfig()
+	if c.ReferenceWeight == nil {
+		c.ReferenceWeight = def.ReferenceWeight
+	}
+	c.Damping = orDefault(c.Damping, def.Damping)
+	c.Tolerance = orDefault(c.Tolerance, def.Tolerance)
+	return c
+}
+
+// identWeight weights the references to a qualified symbol by its name and
+// visibility: mentioned identifiers count more, exported ones more than
+// restricted or private ones. Without visibility, underscore prefixed names
+// are private.
+func (c RankingConfig) identWeight(symbol string, info symbolInfo, mentionedIdents map[string]bool) float64 {
+	switch {
+	case mentionedIdents[symbol] || mentionedIdents[info.name]:
+		return c.MentionedIdentWeight
+	case info.visibility == VisibilityPublic:
+		return c.ExportedWeight
+	case info.visibility == VisibilityProtected || info.visibility == VisibilityPackage:
+		return c.RestrictedWeight
+	case info.visibility == VisibilityPrivate, strings.HasPrefix(info.name, "_"):
+		return c.PrivateIdentWeight
+	default:
+		return 1.0
+	}
+}
+
+// referenceEdgeWeight is the weight of the edges for a symbol referenced
+// from count files.
+func (c RankingConfig) referenceEdgeWeight(symbol string, info symbolInfo, count int, mentionedIdents map[string]bool) float64 {
+	return c.identWeight(symbol, info, mentionedIdents) * c.ReferenceWeight(float64(count))
+}
+
+// ranking returns the ranking config of the RepoMap, DefaultRankingConfig
+// when none is set.
+func (r *RepoMap) ranking() RankingConfig {
+	if r.rankingConfig == nil {
+		return DefaultRankingConfig()
+	}
+	return r.rankingConfig.withDefaults()
+}
@@ -0,0 +1,103 @@
+package germ
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankingConfigDefaults(t *testing.T) {
+	// A zero RepoMap ranks with the default weights
+	var r RepoMap
+	cfg := r.ranking()
+	assert.Equal(t, 10.0, cfg.MentionedIdentWeight)
+	assert.Equal(t, 0.1, cfg.PrivateIdentWeight)
+	assert.Equal(t, 0.85, cfg.Damping)
+	assert.Equal(t, 1e-6, cfg.Tolerance)
+	assert.Equal(t, 100.0, cfg.ChatFileWeight)
+	assert.Equal(t, 0.5, cfg.QueryWeight)
+	assert.Equal(t, 0.5, cfg.SemanticWeight)
+	assert.Equal(t, 2, cfg.ProximityMaxDistance)
+	assert.Equal(t, math.Sqrt(9), cfg.ReferenceWeight(9))
+	assert.Equal(t, PageRank{Damping: 0.85, Tolerance: 1e-6}, r.ranker())
+
+	// Configs start from the defaults
+	cfg = DefaultRankingConfig()
+	cfg.MentionedIdentWeight = 3
+	cfg.Damping = 0.5
+	cfg.ReferenceWeight = func(count float64) float64 { return count }
+	r = *NewRepoMap(".", nil, WithRankingConfig(cfg))
+	cfg = r.ranking()
+	assert.Equal(t, 3.0, cfg.MentionedIdentWeight)
+	assert.Equal(t, 0.1, cfg.PrivateIdentWeight)
+	assert.Equal(t, 9.0, cfg.ReferenceWeight(9))
+	assert.Equal(t, PageRank{Damping: 0.5, Tolerance: 1e-6}, r.ranker())
+
+	// Only the reference weight and the ranker settings cannot be zero
+	cfg = NewRepoMap(".", nil, WithRankingConfig(RankingConfig{})).ranking()
+	assert.Equal(t, 0.0, cfg.MentionedIdentWeight)
+	assert.Equal(t, 0.85, cfg.Damping)
+	assert.Equal(t, 1e-6, cfg.Tolerance)
+	assert.Equal(t, 3.0, cfg.ReferenceWeight(9))
+	assert.Equal(t, PersonalizedPageRank{Damping: 0.9, Tolerance: 1e-6},
+		NewRepoMap(".", nil, WithRankingConfig(RankingConfig{Damping: 0.5}), WithRanker(PersonalizedPageRank{Damping: 0.9})).ranker())
+}
+
+func TestRankingConfigIdentWeight(t *testing.T) {
+	cfg := DefaultRankingConfig()
+	mentioned := map[string]bool{"Render": true}
+
+	assert.Equal(t, 10.0, cfg.identWeight("view.Render", symbolInfo{name: "Render", visibility: VisibilityPrivate}, mentioned))
+	assert.Equal(t, 0.1, cfg.identWeight("_internal", symbolInfo{name: "_internal"}, mentioned))
+	assert.Equal(t, 1.0, cfg.identWeight("Layout", symbolInfo{name: "Layout"}, mentioned))
+
+	// Names containing dots are matched whole, eg. a JavaScript member
+	// definition
+	assert.Equal(t, 10.0, cfg.identWeight("ui.Grid.Render", symbolInfo{name: "Grid.Render"}, map[string]bool{"Grid.Render": true}))
+	assert.Equal(t, 1.0, cfg.identWeight("ui.Grid.Render", symbolInfo{name: "Grid.Render"}, mentioned))
+
+	// Symbols referenced from 4 files
+	assert.Equal(t, 20.0, cfg.referenceEdgeWeight("Render", symbolInfo{name: "Render"}, 4, mentioned))
+	assert.Equal(t, 2.0, cfg.referenceEdgeWeight("Layout", symbolInfo{name: "Layout"}, 4, mentioned))
+}
+
+// TestRankingConfigZeroWeights verifies a zero weight disables its signal.
+func TestRankingConfigZeroWeights(t *testing.T) {
+	cfg := DefaultRankingConfig()
+	cfg.PrivateIdentWeight = 0
+	cfg.QueryWeight = 0
+	cfg.ProximityMaxDistance = 0
+	cfg.RecencyWeight, cfg.ChurnWeight, cfg.AuthorshipWeight = 0, 0, 0
+
+	root, fnames := explainTestFiles(t)
+	r := NewRepoMap(root, nil, WithRankingConfig(cfg), WithProximityWeight(1))
+	cfg = r.ranking()
+
+	// References to private definitions weigh nothing
+	assert.Equal(t, 0.0, cfg.identWeight("app.drain", symbolInfo{name: "drain", visibility: VisibilityPrivate}, nil))
+	assert.Equal(t, 0.0, cfg.referenceEdgeWeight("app.drain", symbolInfo{name: "drain", visibility: VisibilityPrivate}, 4, nil))
+
+	// Only the directory of the anchors is near
+	r.proximityAnchors = []string{"api/handler.go"}
+	assert.Equal(t, 2.0, r.proximityBoost("api/routes.go"))
+	assert.Equal(t, 1.0, r.proximityBoost("api/v2/routes.go"))
+
+	// The history does not boost any file
+	r.history = &GitHistory{
+		Files:      map[string]FileHistory{"ledger.go": {LastModified: time.Now(), Commits: 10, AuthoredCommits: 10}},
+		Until:      time.Now(),
+		maxCommits: 10,
+	}
+	assert.Equal(t, 1.0, r.historyBoost("ledger.go"))
+
+	// The query does not change the map
+	cfg = DefaultRankingConfig()
+	cfg.QueryWeight = 0
+	r = NewRepoMap(root, nil, WithRankingConfig(cfg))
+	plain := r.GetRankedTagsMap(nil, fnames, 60, map[string]bool{}, map[string]bool{})
+	assert.Contains(t, plain, "parseLedger")
+	r.query = "export the report"
+	assert.Equal(t, plain, r.GetRankedTagsMap(nil, fnames, 60, map[string]bool{}, map[string]bool{}))
+}
@@ -0,0 +1,345 @@
+package germ
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	queries "github.com/cyber-nic/germ/queries"
+	grepast "github.com/cyber-nic/grep-ast"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// RelationExtends is a class extending a class, or an interface or trait
+	// extending another
+	RelationExtends = "extends"
+	// RelationImplements is a type implementing an interface or trait,
+	// explicitly or, in Go, through its method set
+	RelationImplements = "implements"
+	// RelationEmbeds is a Go struct or interface embedding another type
+	RelationEmbeds = "embeds"
+)
+
+// Relation is a typed relation between two types, eg. Server implements Handler.
+type Relation struct {
+	// FileName and Line locate the subtype declaration
+	FileName string
+	Line     int
+	// Name is the qualified subtype, eg. srv.Server
+	Name string
+	// Target is the supertype as written, eg. Handler or Reader, or its
+	// qualified name for structural relations
+	Target string
+	// Kind is one of RelationExtends, RelationImplements or RelationEmbeds
+	Kind string
+}
+
+// LoadRelationsQuery loads the relations query for a language.
+func (r *RepoMap) LoadRelationsQuery(lang *sitter.Language, langID string) (*sitter.Query, error) {
+	querySource, err := queries.GetRelationsQuery(queries.SitterLanguage(langID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain relations query (%s): %w", langID, err)
+	}
+	if len(querySource) == 0 {
+		return nil, fmt.Errorf("empty relations query file: %s", langID)
+	}
+
+	return compileQuery(lang, querySource)
+}
+
+// GetRelationsRaw parses the file with Tree-sitter and extracts the relations
+// its types declare.
+func (r *RepoMap) GetRelationsRaw(fname, relFname string) ([]Relation, error) {
+	lang, langID, err := getLanguageFromFileName(fname, nil)
+	if err != nil || lang == nil {
+		return nil, grepast.ErrorUnsupportedLanguage
+	}
+
+	q, err := r.LoadRelationsQuery(lang, langID)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	sourceCode, err := readSourceCode(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(lang)
+
+	tree := parser.Parse(sourceCode, nil)
+	if tree == nil || tree.RootNode() == nil {
+		return nil, fmt.Errorf("failed to parse file: %s", fname)
+	}
+	defer tree.Close()
+
+	prefix := r.scopePrefix(langID, relFname)
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+
+	type relKey struct {
+		name, target, kind string
+	}
+	seen := make(map[relKey]struct{})
+
+	var rels []Relation
+	matches := qc.Matches(q, tree.RootNode(), sourceCode)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var name *sitter.Node
+		var targets []sitter.QueryCapture
+		for _, c := range match.Captures {
+			capture := q.CaptureNames()[c.Index]
+			switch {
+			case capture == "name":
+				name = &c.Node
+			case strings.HasPrefix(capture, "relation."):
+				targets = append(targets, c)
+			}
+		}
+		if name == nil {
+			continue
+		}
+
+		scope := append(append([]string{}, prefix...), tagScope(name, sourceCode, TagKindDef)...)
+		subtype := Tag{Name: name.Utf8Text(sourceCode), Scope: scope}.QualifiedName()
+
+		for _, c := range targets {
+			k := relKey{
+				name:   subtype,
+				target: c.Node.Utf8Text(sourceCode),
+				kind:   strings.TrimPrefix(q.CaptureNames()[c.Index], "relation."),
+			}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+
+			rels = append(rels, Relation{
+				FileName: relFname,
+				Line:     int(name.StartPosition().Row),
+				Name:     k.name,
+				Target:   k.target,
+				Kind:     k.kind,
+			})
+		}
+	}
+
+	return rels, nil
+}
+
+// GetRelations returns the relations declared by the types of the given
+// files, plus the Go interfaces implemented by each type's method set.
+func (r *RepoMap) GetRelations(fnames []string) []Relation {
+	return r.getRelations(fnames, r.getTagsFromFiles(fnames, commonWords))
+}
+
+// getRelations returns the declared and structural relations of the files,
+// sorted by file, line, then target.
+func (r *RepoMap) getRelations(fnames []string, allTags []Tag) []Relation {
+	var rels []Relation
+	for _, fname := range fnames {
+		fr, err := r.GetRelationsRaw(fname, r.GetRelFname(fname))
+		if err != nil {
+			log.Trace().Err(err).Str("file", fname).Msg("relations")
+			continue
+		}
+		rels = append(rels, fr...)
+	}
+
+	deps := r.deps
+	if deps == nil {
+		deps = r.GetDependencyGraph(fnames)
+	}
+	rels = append(rels, goStructuralRelations(allTags, rels, deps)...)
+
+	sort.SliceStable(rels, func(i, j int) bool {
+		if rels[i].FileName != rels[j].FileName {
+			return rels[i].FileName < rels[j].FileName
+		}
+		if rels[i].Line != rels[j].Line {
+			return rels[i].Line < rels[j].Line
+		}
+		return rels[i].Target < rels[j].Target
+	})
+	return rels
+}
+
+// goStructuralRelations returns an implements relation from each Go type to
+// each non-empty Go interface whose methods its method set includes. Method
+// sets include the methods promoted from embedded types. Methods are matched
+// by name only, their signatures are ignored, so only interfaces in the
+// type's package, or in a package it imports per deps, are considered.
+func goStructuralRelations(allTags []Tag, declared []Relation, deps *DependencyGraph) []Relation {
+	methods := make(map[string]map[string]struct{}) // qualified owner -> method names
+	interfaces := make(map[string]Tag)
+	types := make(map[string]Tag)
+
+	for _, t := range allTags {
+		if t.Kind != TagKindDef || filepath.Ext(t.FileName) != ".go" {
+			continue
+		}
+		switch t.SubKind {
+		case "method":
+			owner := strings.Join(t.Scope, ".")
+			if methods[owner] == nil {
+				methods[owner] = make(map[string]struct{})
+			}
+			methods[owner][t.Name] = struct{}{}
+		case "interface":
+			interfaces[t.QualifiedName()] = t
+		case "type":
+			types[t.QualifiedName()] = t
+		}
+	}
+
+	// Embedded types, qualified within the embedding package when defined there
+	embeds := make(map[string][]string)
+	for _, rel := range declared {
+		if rel.Kind != RelationEmbeds || filepath.Ext(rel.FileName) != ".go" {
+			continue
+		}
+		target := rel.Target
+		if i := strings.LastIndex(rel.Name, "."); i >= 0 {
+			local := rel.Name[:i] + "." + rel.Target
+			_, isType := types[local]
+			_, isInterface := interfaces[local]
+			if _, hasMethods := methods[local]; isType || isInterface || hasMethods {
+				target = local
+			}
+		}
+		embeds[rel.Name] = append(embeds[rel.Name], target)
+	}
+
+	// Method sets are computed once per type, visiting guarding against
+	// embedding cycles in invalid code
+	sets := make(map[string]map[string]struct{})
+	visiting := make(map[string]struct{})
+	var methodSet func(name string) map[string]struct{}
+	methodSet = func(name string) map[string]struct{} {
+		if set, ok := sets[name]; ok {
+			return set
+		}
+		set := make(map[string]struct{})
+		if _, ok := visiting[name]; ok {
+			return set
+		}
+		visiting[name] = struct{}{}
+		defer delete(visiting, name)
+
+		for m := range methods[name] {
+			set[m] = struct{}{}
+		}
+		for _, e := range embeds[name] {
+			for m := range methodSet(e) {
+				set[m] = struct{}{}
+			}
+		}
+		sets[name] = set
+		return set
+	}
+
+	// Packages, ie. directories, imported by each package
+	imported := make(map[string]map[string]struct{})
+	if deps != nil {
+		for src, dsts := range deps.Files {
+			dir := filepath.Dir(src)
+			for _, dst := range dsts {
+				if imported[dir] == nil {
+					imported[dir] = make(map[string]struct{})
+				}
+				imported[dir][filepath.Dir(dst)] = struct{}{}
+			}
+		}
+	}
+
+	var rels []Relation
+	for iname, it := range interfaces {
+		required := methodSet(iname)
+		if len(required) == 0 {
+			continue
+		}
+		idir := filepath.Dir(it.FileName)
+		for tname, t := range types {
+			if tdir := filepath.Dir(t.FileName); tdir != idir {
+				if _, ok := imported[tdir][idir]; !ok {
+					continue
+				}
+			}
+			have := methodSet(tname)
+			if len(have) < len(required) {
+				continue
+			}
+			satisfies := true
+			for m := range required {
+				if _, ok := have[m]; !ok {
+					satisfies = false
+					break
+				}
+			}
+			if satisfies {
+				rels = append(rels, Relation{
+					FileName: t.FileName,
+					Line:     t.Line,
+					Name:     tname,
+					Target:   iname,
+					Kind:     RelationImplements,
+				})
+			}
+		}
+	}
+	return rels
+}
+
+// relationTargets returns the files defining the supertype of a relation,
+// narrowed by the import graph of the subtype's file when there are several.
+func relationTargets(rel Relation, defines map[string]map[string]struct{}, qualifiedByName map[string][]string, deps *DependencyGraph) []string {
+	var symbols []string
+	if _, ok := defines[rel.Target]; ok {
+		symbols = []string{rel.Target}
+	} else {
+		symbols = resolveReference(Tag{Name: targetName(rel.Target)}, qualifiedByName[targetName(rel.Target)])
+	}
+
+	var files []string
+	for _, symbol := range narrowByImports(deps, rel.FileName, symbols, defines) {
+		for f := range defines[symbol] {
+			files = appendUnique(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// getRelationEdges returns an edge from the file of each supertype to the
+// subtype in its file, so rank flows from an interface or base class to its
+// implementations, weighted by the RepoMap relation weight.
+func (r *RepoMap) getRelationEdges(allTags []Tag) []fileEdge {
+	defines, qualifiedByName := r.indexDefinitions(allTags)
+
+	var edges []fileEdge
+	for _, rel := range r.relations {
+		for _, f := range relationTargets(rel, defines, qualifiedByName, r.deps) {
+			if f != rel.FileName {
+				edges = append(edges, fileEdge{src: f, dst: rel.FileName, weight: r.relationWeight, kind: rel.Kind, symbol: rel.Name})
+			}
+		}
+	}
+	return edges
+}
+
+// targetName returns the name of a supertype written with its qualifier, eg.
+// Reader for io.Reader.
+func targetName(target string) string {
+	if i := strings.LastIndex(target, "."); i >= 0 && i < len(target)-1 {
+		return target[i+1:]
+	}
+	return target
+}
@@ -0,0 +1,151 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetRelationsRaw verifies extends, implements and embeds relations are
+// extracted per language, with qualified subtype names.
+func TestGetRelationsRaw(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"srv/server.go": "package srv\n\ntype Server struct {\n\tBase\n\t*log.Logger\n\tname string\n}\n\ntype Handler interface {\n\tio.Closer\n\tServe()\n}\n",
+		"App.java":      "package com.x;\n\nclass App extends Base<T> implements Runnable, Comparable<App> {}\n",
+		"app.ts":        "class App extends Base implements Service {}\ninterface Service extends Closer {}\n",
+		"app.py":        "class App(Base, mixins.Log, metaclass=Meta):\n    pass\n",
+		"lib.rs":        "impl fmt::Display for App {}\ntrait Sub: Super {}\n",
+		"app.cpp":       "class App : public Base, private ns::Mixin {};\n",
+	})
+
+	tests := []struct {
+		file string
+		want []Relation
+	}{
+		{"srv/server.go", []Relation{
+			{FileName: "srv/server.go", Line: 2, Name: "srv.Server", Target: "Base", Kind: RelationEmbeds},
+			{FileName: "srv/server.go", Line: 2, Name: "srv.Server", Target: "Logger", Kind: RelationEmbeds},
+			{FileName: "srv/server.go", Line: 8, Name: "srv.Handler", Target: "Closer", Kind: RelationEmbeds},
+		}},
+		{"App.java", []Relation{
+			{FileName: "App.java", Line: 2, Name: "com.x.App", Target: "Base", Kind: RelationExtends},
+			{FileName: "App.java", Line: 2, Name: "com.x.App", Target: "Runnable", Kind: RelationImplements},
+			{FileName: "App.java", Line: 2, Name: "com.x.App", Target: "Comparable", Kind: RelationImplements},
+		}},
+		{"app.ts", []Relation{
+			{FileName: "app.ts", Line: 0, Name: "App", Target: "Base", Kind: RelationExtends},
+			{FileName: "app.ts", Line: 0, Name: "App", Target: "Service", Kind: RelationImplements},
+			{FileName: "app.ts", Line: 1, Name: "Service", Target: "Closer", Kind: RelationExtends},
+		}},
+		{"app.py", []Relation{
+			{FileName: "app.py", Line: 0, Name: "App", Target: "Base", Kind: RelationExtends},
+			{FileName: "app.py", Line: 0, Name: "App", Target: "Log", Kind: RelationExtends},
+		}},
+		{"lib.rs", []Relation{
+			{FileName: "lib.rs", Line: 0, Name: "App", Target: "Display", Kind: RelationImplements},
+			{FileName: "lib.rs", Line: 1, Name: "Sub", Target: "Super", Kind: RelationExtends},
+		}},
+		{"app.cpp", []Relation{
+			{FileName: "app.cpp", Line: 0, Name: "App", Target: "Base", Kind: RelationExtends},
+			{FileName: "app.cpp", Line: 0, Name: "App", Target: "Mixin", Kind: RelationExtends},
+		}},
+	}
+
+	r := &RepoMap{root: root}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			rels, err := r.GetRelationsRaw(root+"/"+tt.file, tt.file)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rels)
+		})
+	}
+}
+
+// TestGoStructuralRelations verifies Go types implement the interfaces their
+// method sets, including promoted methods, satisfy.
+func TestGoStructuralRelations(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"store/store.go": "package store\n\ntype Reader interface {\n\tLookup(key string) string\n}\n\ntype ReadWriter interface {\n\tReader\n\tStore(key, value string)\n}\n",
+		"store/mem.go":   "package store\n\ntype backend struct{}\n\nfunc (backend) Lookup(key string) string { return key }\n\ntype Memory struct {\n\tbackend\n}\n\nfunc (m *Memory) Store(key, value string) {}\n",
+		"store/nop.go":   "package store\n\ntype Nop struct{}\n\nfunc (Nop) Store(key, value string) {}\n",
+	})
+
+	r := &RepoMap{root: root}
+	var implements []string
+	for _, rel := range r.GetRelations(fnames) {
+		if rel.Kind == RelationImplements {
+			implements = append(implements, rel.Name+" "+rel.Target)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"store.backend store.Reader",
+		"store.Memory store.Reader",
+		"store.Memory store.ReadWriter",
+	}, implements)
+}
+
+// TestGoStructuralRelationsImports verifies Go types only implement the
+// interfaces of their package or of the packages they import.
+func TestGoStructuralRelationsImports(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"store/store.go":  "package store\n\ntype Reader interface {\n\tLookup(key string) string\n}\n",
+		"cache/lru.go":    "package cache\n\nimport \"example.com/app/store\"\n\nvar _ store.Reader = LRU{}\n\ntype LRU struct{}\n\nfunc (LRU) Lookup(key string) string { return key }\n",
+		"cache/ttl.go":    "package cache\n\ntype TTL struct{}\n\nfunc (TTL) Lookup(key string) string { return key }\n",
+		"dns/resolver.go": "package dns\n\ntype Resolver struct{}\n\nfunc (Resolver) Lookup(host string) string { return host }\n",
+	})
+
+	r := &RepoMap{root: root}
+	var implements []string
+	for _, rel := range r.GetRelations(fnames) {
+		if rel.Kind == RelationImplements {
+			implements = append(implements, rel.Name+" "+rel.Target)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"cache.LRU store.Reader",
+		"cache.TTL store.Reader",
+	}, implements)
+}
+
+// TestRelationRanking verifies an interface's file references its
+// implementations, and links to their files, when relation edges are enabled.
+func TestRelationRanking(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"main.go":       "package main\n\nfunc run() {\n\tstore.Open().Fetch()\n}\n",
+		"store/api.go":  "package store\n\ntype Storage interface {\n\tFetch() string\n}\n\nfunc Open() Storage { return nil }\n",
+		"store/disk.go": "package store\n\ntype Disk struct{}\n\nfunc (Disk) Fetch() string { return \"\" }\n",
+	})
+
+	references := func(r *RepoMap) map[string][]string {
+		allTags := r.getTagsFromFiles(fnames, commonWords)
+		r.fileEdges = r.getFileEdges(fnames, allTags)
+		_, refs, _, _ := r.buildReferenceMaps(allTags)
+		return refs
+	}
+
+	// Relations are only weighted file edges, not references
+	r := &RepoMap{root: root, relationWeight: 1}
+	assert.NotContains(t, references(r)["store.Disk"], "store/api.go")
+	assert.Contains(t, r.fileEdges, fileEdge{src: "store/api.go", dst: "store/disk.go", weight: 1, kind: RelationImplements, symbol: "store.Disk"})
+
+	// The subtype ranks through the relation, in proportion to its weight
+	diskRank := func(opts ...func(*RepoMap)) float64 {
+		r := NewRepoMap(root, nil, opts...)
+		r.GetRankedTagsMap(nil, fnames, 0, map[string]bool{}, map[string]bool{})
+		e, err := r.Explain("store.Disk")
+		if !assert.NoError(t, err) || !assert.Len(t, e, 1) {
+			return 0
+		}
+		return e[0].Rank
+	}
+	base := diskRank()
+	low, high := diskRank(WithRelationWeight(0.01)), diskRank(WithRelationWeight(1))
+	assert.Greater(t, low, base)
+	assert.Greater(t, high-base, 10*(low-base))
+	assert.Greater(t, diskRank(WithRelationWeight(1), WithSymbolRanking(true)), diskRank(WithSymbolRanking(true)))
+}
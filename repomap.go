@@ -24,7 +24,6 @@ import (
 	"github.com/rs/zerolog/log"
 	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/multi"
-	"gonum.org/v1/gonum/graph/network"
 )
 
 //go:embed .astignore
@@ -46,8 +45,19 @@ type Tag struct {
 	FileName string
 	FilePath string
 	Line     int
-	Name     string
-	Kind     string
+	// EndLine is the last line of a definition's @definition node, or Line
+	EndLine int
+	Name    string
+	Kind    string
+	// SubKind is the capture suffix following the kind, eg. function, method,
+	// constant, field, call, import, etc.
+	SubKind string
+	// Scope is the chain of enclosing scopes of a definition (package, class,
+	// receiver type, namespace, ...) or the qualifier of a reference.
+	Scope []string
+	// Visibility is the visibility of a definition, eg. VisibilityPublic, or
+	// empty for references and languages without known visibility rules.
+	Visibility string
 }
 
 // RepoMap default options
@@ -84,6 +94,59 @@ type RepoMap struct {
 	mapShowLastLine           bool
 	mapMarkLinesOfInterest    bool
 	mapLinesOfInterestPadding int
+	// ranking options
+	importWeight   float64
+	relationWeight float64
+	symbolRanking  bool
+	// rankerImpl scores the reference graph, PageRank when nil
+	rankerImpl Ranker
+	// rankingConfig holds the graph weights, see RankingConfig, nil for the
+	// defaults
+	rankingConfig *RankingConfig
+	// historyWindow is the git history window boosting edited files, if any
+	historyWindow time.Duration
+	// history is the git history read for the current ranking, if enabled
+	history *GitHistory
+	// coChangeWeight is the weight of the edges between files committed
+	// together, zero when disabled
+	coChangeWeight float64
+	// proximityWeight is the weight of the proximity to the chat and
+	// mentioned files in the personalization, zero when disabled
+	proximityWeight float64
+	// proximityAnchors are the files the proximity is measured from
+	proximityAnchors []string
+	// lastRanking records the last ranking for Explain
+	lastRanking *rankTrace
+	// linesOfInterest are extra 0-based lines to show per relative file, eg.
+	// the frames of a stack trace
+	linesOfInterest map[string][]int
+	// parsedTags are the tags of the files of the next map when already
+	// parsed, eg. to extract the mentions, see GetRankedTagsMap
+	parsedTags []Tag
+	// query is the natural-language query blended into the ranking, if any
+	query string
+	// embedder embeds definitions and queries for semantic ranking, if set
+	embedder Embedder
+	// embeddingCache is the file persisting the definition vectors, if any
+	embeddingCache string
+	// sources caches the lines of the files read for doc comments and
+	// snippets, see sourceLines
+	sources map[string]cachedLines
+	// rustCrates caches the nearest Cargo package of each directory, read
+	// once per set of tags, see rustCrateOf
+	rustCrates map[string]*rustCrate
+	// pythonRoots are extra Python source roots searched for imports
+	pythonRoots []string
+	// compileCommands is the path of the C/C++ compilation database
+	compileCommands string
+	// includeDirs are extra C/C++ include search directories
+	includeDirs []string
+	// fileEdges are the non-identifier edges added to the file graph
+	fileEdges []fileEdge
+	// deps is the import graph used to disambiguate references, if enabled
+	deps *DependencyGraph
+	// relations are the type relations linking supertypes to subtypes, if enabled
+	relations []Relation
 }
 
 // NewRepoMap is the repo map constructor.
@@ -205,7 +268,8 @@ func WithMapMulNoFiles(value int) func(*RepoMap) {
 	}
 }
 
-// WithMaxTokens sets the map's maximum number of tokens.
+// WithMaxTokens sets the map's maximum number of tokens. Generate keeps the
+// best ranked definitions that fit, see GetRankedTagsMap.
 func WithMaxTokens(value int) func(*RepoMap) {
 	return func(o *RepoMap) {
 		o.maxMapTokens = value
@@ -254,6 +318,70 @@ func WithLinesOfInterestPadding(value int) func(*RepoMap) {
 	}
 }
 
+// WithImportWeight links each file to the repository files it imports with
+// an edge of the given weight when ranking, and links references to the
+// definitions they import when a symbol is defined in several files. Zero
+// disables import resolution.
+func WithImportWeight(value float64) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.importWeight = value
+	}
+}
+
+// WithRelationWeight links the file of each supertype (base class, interface
+// or trait) to the files of its subtypes with an edge of the given weight
+// when ranking, so an interface pulls its implementations into the map. Zero
+// disables relation extraction.
+func WithRelationWeight(value float64) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.relationWeight = value
+	}
+}
+
+// WithSymbolRanking ranks definitions rather than files: references link the
+// definition enclosing them to the definitions they point to, so the map
+// selects the most central functions instead of everything in central files.
+func WithSymbolRanking(value bool) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.symbolRanking = value
+	}
+}
+
+// WithRanker sets the algorithm scoring the reference graph, eg.
+// PersonalizedPageRank, HITS or InDegree. It defaults to PageRank, or
+// PersonalizedPageRank with the directory proximity, see WithProximityWeight.
+func WithRanker(value Ranker) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.rankerImpl = value
+	}
+}
+
+// WithPythonSourceRoots adds directories searched for absolute Python
+// imports, before the repository root and src/ layouts, eg. lib or
+// services/api.
+func WithPythonSourceRoots(roots ...string) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.pythonRoots = append(o.pythonRoots, roots...)
+	}
+}
+
+// WithCompileCommands sets the path of the compile_commands.json used to
+// resolve C/C++ includes. By default compile_commands.json and
+// build/compile_commands.json under the root are tried.
+func WithCompileCommands(path string) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.compileCommands = path
+	}
+}
+
+// WithIncludeDirs adds C/C++ include search directories, used after those of
+// the compilation database and for files it does not list.
+func WithIncludeDirs(dirs ...string) func(*RepoMap) {
+	return func(o *RepoMap) {
+		o.includeDirs = append(o.includeDirs, dirs...)
+	}
+}
+
 // Verbose enables verbose output for debugging.
 func Verbose(value bool) func(*RepoMap) {
 	return func(o *RepoMap) {
@@ -325,6 +453,37 @@ func (r *RepoMap) LoadQuery(lang *sitter.Language, langID string) (*sitter.Query
 		return nil, fmt.Errorf("empty query file: %s", langID)
 	}
 
+	return compileQuery(lang, querySource)
+}
+
+// LoadLocalsQuery loads the Tree-sitter locals query text and compiles a sitter.Query.
+func (r *RepoMap) LoadLocalsQuery(lang *sitter.Language, langID string) (*sitter.Query, error) {
+	querySource, err := queries.GetLocalsQuery(queries.SitterLanguage(langID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain locals query (%s): %w", langID, err)
+	}
+	if len(querySource) == 0 {
+		return nil, fmt.Errorf("empty locals query file: %s", langID)
+	}
+
+	return compileQuery(lang, querySource)
+}
+
+// LoadImportsQuery loads the Tree-sitter imports query text and compiles a sitter.Query.
+func (r *RepoMap) LoadImportsQuery(lang *sitter.Language, langID string) (*sitter.Query, error) {
+	querySource, err := queries.GetImportsQuery(queries.SitterLanguage(langID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain imports query (%s): %w", langID, err)
+	}
+	if len(querySource) == 0 {
+		return nil, fmt.Errorf("empty imports query file: %s", langID)
+	}
+
+	return compileQuery(lang, querySource)
+}
+
+// compileQuery compiles the query source, unwrapping sitter.QueryError details.
+func compileQuery(lang *sitter.Language, querySource []byte) (*sitter.Query, error) {
 	q, qErr := sitter.NewQuery(lang, string(querySource))
 	if qErr != nil {
 		var queryErr *sitter.QueryError
@@ -342,6 +501,20 @@ func (r *RepoMap) LoadQuery(lang *sitter.Language, langID string) (*sitter.Query
 	return q, nil
 }
 
+// definitionEndLine returns the last line of the @definition node enclosing a
+// definition's name in the same match, or line when there is none.
+func definitionEndLine(q *sitter.Query, match *sitter.QueryMatch, kind string, line int) int {
+	if kind != TagKindDef {
+		return line
+	}
+	for _, c := range match.Captures {
+		if strings.HasPrefix(q.CaptureNames()[c.Index], "definition.") {
+			return int(c.Node.EndPosition().Row)
+		}
+	}
+	return line
+}
+
 // readSourceCode reads the source code from a file.
 func readSourceCode(fname string) ([]byte, error) {
 	sourceCode, err := os.ReadFile(fname)
@@ -363,6 +536,15 @@ type TagFilter func(name string) bool
 // definitions (def) and references (ref). All other captures are ignored.
 // filter is a function that accepts the name of a capture and returns bool false if it should be skipped.
 func GetTagsFromQueryCapture(relFname, fname string, q *sitter.Query, tree *sitter.Tree, sourceCode []byte, filter TagFilter) []Tag {
+	_, langID, _ := getLanguageFromFileName(fname, sourceCode)
+	return getTagsFromQueryCapture(relFname, fname, langID, q, tree, sourceCode, filter, nil)
+}
+
+// getTagsFromQueryCapture is GetTagsFromQueryCapture with the language of the
+// file, used to derive the visibility of definitions, and an additional set
+// of node start bytes (see resolveLocals) whose references are dropped
+// because they resolve to a local binding.
+func getTagsFromQueryCapture(relFname, fname, langID string, q *sitter.Query, tree *sitter.Tree, sourceCode []byte, filter TagFilter, localRefs map[uint]struct{}) []Tag {
 
 	// Create a new query cursor that will be used to iterate through
 	// the captures of our query on the provided parse tree. The query
@@ -377,6 +559,16 @@ func GetTagsFromQueryCapture(relFname, fname string, q *sitter.Query, tree *sitt
 
 	tags := []Tag{}
 
+	// A node may be matched by more than one pattern (eg. a selector that is
+	// both a call and a field access). Only the first, most specific, capture
+	// of a given kind is kept for each node.
+	type seenKey struct {
+		startByte uint
+		kind      string
+		name      string
+	}
+	seen := make(map[seenKey]struct{})
+
 	// Iterate over all of the query results (i.e., the captures). The Next
 	// method returns a matched result (match) and the index of the capture
 	// (index) within that match. Continue iterating until match is nil.
@@ -396,40 +588,64 @@ func GetTagsFromQueryCapture(relFname, fname string, q *sitter.Query, tree *sitt
 		row := int(c.Node.StartPosition().Row)
 
 		// Extract the raw text from the matched node in the source code. We
-		// convert it from a slice of bytes to a string.
-		name := string(c.Node.Utf8Text(sourceCode))
+		// convert it from a slice of bytes to a string. Some captures hold a
+		// whitespace separated list of names (eg. an HTML class attribute),
+		// each of which becomes its own tag.
+		for _, name := range strings.Fields(string(c.Node.Utf8Text(sourceCode))) {
+
+			// Go imports are referred to by their package name
+			if langID == "go" && tag == "name.reference.import" {
+				name = goPackageName(name)
+			}
 
-		// Allows a user-provided list of terms to skip: eg. bool, string, etc.
-		if filter != nil && !filter(name) {
-			continue
-		}
+			// Allows a user-provided list of terms to skip: eg. bool, string, etc.
+			if filter != nil && !filter(name) {
+				continue
+			}
 
-		// Determine if the capture corresponds to a definition or a reference
-		// by checking prefixes in its name. If neither condition matches, we
-		// skip it.
-		switch {
-		case strings.HasPrefix(tag, "name.definition."):
-			// eg. function, method, type, etc.
-			tags = append(tags, Tag{
-				Name:     name,
-				FileName: relFname,
-				FilePath: fname,
-				Line:     row,
-				Kind:     TagKindDef,
-			})
+			// Determine if the capture corresponds to a definition or a reference
+			// by checking prefixes in its name. If neither condition matches, we
+			// skip it.
+			var kind, subKind string
+			switch {
+			case strings.HasPrefix(tag, "name.definition."):
+				// eg. function, method, type, etc.
+				kind, subKind = TagKindDef, strings.TrimPrefix(tag, "name.definition.")
+
+			case strings.HasPrefix(tag, "name.reference."):
+				// Skip references to local variables and parameters
+				if _, ok := localRefs[c.Node.StartByte()]; ok {
+					continue
+				}
+				//eg. function call, type usage, etc.
+				kind, subKind = TagKindRef, strings.TrimPrefix(tag, "name.reference.")
+
+			default:
+				continue
+			}
+
+			k := seenKey{startByte: c.Node.StartByte(), kind: kind, name: name}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+
+			var visibility string
+			if kind == TagKindDef {
+				visibility = tagVisibility(&c.Node, sourceCode, langID)
+			}
 
-		case strings.HasPrefix(tag, "name.reference."):
-			//eg. function call, type usage, etc.
 			tags = append(tags, Tag{
-				Name:     name,
-				FileName: relFname,
-				FilePath: fname,
-				Line:     row,
-				Kind:     TagKindRef,
+				Name:       name,
+				FileName:   relFname,
+				FilePath:   fname,
+				Line:       row,
+				EndLine:    definitionEndLine(q, match, kind, row),
+				Kind:       kind,
+				SubKind:    subKind,
+				Scope:      tagScope(&c.Node, sourceCode, kind),
+				Visibility: visibility,
 			})
-
-		default:
-			// continue
 		}
 	}
 
@@ -439,7 +655,7 @@ func GetTagsFromQueryCapture(relFname, fname string, q *sitter.Query, tree *sitt
 // GetTagsRaw parses the file with Tree-sitter and extracts "function definitions"
 func (r *RepoMap) GetTagsRaw(fname, relFname string, filter TagFilter) ([]Tag, error) {
 	// 1) Identify the file's language
-	lang, langID, err := grepast.GetLanguageFromFileName(fname)
+	lang, langID, err := getLanguageFromFileName(fname, nil)
 	if err != nil || lang == nil {
 		return nil, grepast.ErrorUnsupportedLanguage
 	}
@@ -467,22 +683,49 @@ func (r *RepoMap) GetTagsRaw(fname, relFname string, filter TagFilter) ([]Tag, e
 	}
 	defer q.Close()
 
-	// 6) Execute the query
-	qc := sitter.NewQueryCursor()
-	defer qc.Close()
+	// 6) Resolve local bindings, when the language has a locals query
+	var localRefs map[uint]struct{}
+	lq, err := r.LoadLocalsQuery(lang, langID)
+	if err == nil {
+		localRefs = resolveLocals(lq, tree, sourceCode)
+		lq.Close()
+	} else {
+		log.Trace().Err(err).Str("lang", langID).Msg("no locals")
+	}
 
 	// Get the tags from the query capture and source code
-	tags := GetTagsFromQueryCapture(relFname, fname, q, tree, sourceCode, filter)
+	tags := getTagsFromQueryCapture(relFname, fname, langID, q, tree, sourceCode, filter, localRefs)
+
+	// Qualify definitions by the modules the file layout declares, if any
+	if prefix := r.scopePrefix(langID, relFname); prefix != nil {
+		for i := range tags {
+			if tags[i].Kind == TagKindDef {
+				tags[i].Scope = append(append([]string{}, prefix...), tags[i].Scope...)
+			}
+		}
+	}
 
 	// 7) Return the list of Tag objects
 	return tags, nil
 }
 
+// scopePrefix returns the scope qualifying every definition of a file when
+// its language declares modules through the file layout, ie. Rust.
+func (r *RepoMap) scopePrefix(langID, relFname string) []string {
+	if langID == "rust" {
+		return r.rustScope(relFname)
+	}
+	return nil
+}
+
 // getTagsFromFiles collect all tags from those files
 func (r *RepoMap) getTagsFromFiles(allFnames []string, ignoreWords map[string]struct{}) []Tag {
 
 	var allTags []Tag
 
+	// Manifests may have changed since the last set of tags
+	r.rustCrates = nil
+
 	for _, fname := range allFnames {
 		log.Trace().Str("file", fname).Msg("tags")
 		// Get the relative file name
@@ -511,12 +754,6 @@ func (r *RepoMap) getTagsFromFiles(allFnames []string, ignoreWords map[string]st
 			continue
 		}
 
-		// ndelorme - file tags
-		// fmt.Println("Tags for file:", fname)
-		// for _, t := range tg {
-		// 	fmt.Printf("- %s / %d / %s\n", t.Kind, t.Line, t.Name)
-		// }
-
 		if tg != nil {
 			allTags = append(allTags, tg...)
 		}
@@ -527,7 +764,7 @@ func (r *RepoMap) getTagsFromFiles(allFnames []string, ignoreWords map[string]st
 
 type tagKey struct {
 	fname  string // the file name (relative)
-	symbol string // the actual identifier
+	symbol string // the qualified identifier, see Tag.QualifiedName
 }
 
 func (r *RepoMap) getRankedTagsByPageRank(allTags []Tag, mentionedFnames, mentionedIdents map[string]bool) []Tag {
@@ -537,32 +774,21 @@ func (r *RepoMap) getRankedTagsByPageRank(allTags []Tag, mentionedFnames, mentio
 	//--------------------------------------------------------
 	defines, references, definitions, identifiers := r.buildReferenceMaps(allTags)
 
-	if r.verbose {
-		// ndelorme
-		fmt.Printf("\n\n## defines:")
-		for k, v := range defines {
-			fmt.Printf("\n- %s: %v", k, v)
-		}
-		fmt.Printf("\n\n## definitions:")
-		for k, v := range definitions {
-			fmt.Printf("\n- %s: %v", k, v)
-		}
-		fmt.Printf("\n\n## references:")
-		for k, v := range references {
-			fmt.Printf("\n- %s: %v", k, v)
-		}
-		fmt.Printf("\n\n## idents:")
-		for k := range identifiers {
-			fmt.Printf("\n- %s", k)
-		}
-	}
+	log.Trace().
+		Int("defines", len(defines)).
+		Int("definitions", len(definitions)).
+		Int("references", len(references)).
+		Int("identifiers", len(identifiers)).
+		Msg("reference maps")
 
 	//--------------------------------------------------------
 	// 2) Construct a multi-directed graph
 	//--------------------------------------------------------
-	g, nodeByFile, fileSet := r.buildFileGraph(defines, references, identifiers, mentionedIdents)
+	infos := symbolInfos(allTags)
+	g, nodeByFile, fileSet, edges := r.buildFileGraph(defines, references, identifiers, infos, mentionedIdents)
 
 	// 4) Personalization
+	cfg := r.ranking()
 	personal := make(map[int64]float64)
 	totalFiles := float64(len(fileSet))
 	defaultPersonal := 1.0 / totalFiles
@@ -574,34 +800,29 @@ func (r *RepoMap) getRankedTagsByPageRank(allTags []Tag, mentionedFnames, mentio
 
 	for f, node := range nodeByFile {
 		if _, inChat := chatSet[f]; inChat {
-			personal[node.ID()] = 100.0 / totalFiles
+			personal[node.ID()] = cfg.ChatFileWeight / totalFiles
 		} else {
 			personal[node.ID()] = defaultPersonal
 		}
+		personal[node.ID()] *= r.proximityBoost(f)
 	}
 
-	// 5) Run PageRank (NOTE: gonum.network.PageRank might not natively handle personalization
-	// the same way. If you need full personalized PageRank, you might have to modify or implement
-	// your own. For now, we do unpersonalized for demonstration.)
-	pr := network.PageRank(g, 0.85, 1e-6) // no direct personalization used
+	// 5) Score the files, only personalized rankers use the chat files
+	pr := r.ranker().Rank(g, personal)
 
 	//--------------------------------------------------------
 	// 3) Distribute each file’s rank across its out-edges
 	//--------------------------------------------------------
-	edgeRanks := distributeRank(pr, defines, references, nodeByFile, mentionedIdents)
-
-	if r.verbose {
-		fmt.Printf("\n\n## Ranked defs:")
-		for edge, rank := range edgeRanks {
-			fmt.Printf("\n- %v / %s / %s", rank, edge.dst, edge.symbol)
-		}
-	}
+	edgeRanks := distributeRank(pr, edges, nodeByFile)
 
 	//--------------------------------------------------------
 	// 4) Convert edge-based rank to a sorted list
 	//--------------------------------------------------------
 	defRankSlice := toDefRankSlice(edgeRanks)
 
+	// Blend in the relevance to the query, if any
+	defRankSlice, relevance := r.blendQuery(defRankSlice, allTags)
+
 	// 8) Sort by rank, then by fname, then by symbol
 	sort.Slice(defRankSlice, func(i, j int) bool {
 		if defRankSlice[i].rank != defRankSlice[j].rank {
@@ -613,6 +834,17 @@ func (r *RepoMap) getRankedTagsByPageRank(allTags []Tag, mentionedFnames, mentio
 		return defRankSlice[i].symbol < defRankSlice[j].symbol
 	})
 
+	// Record the ranking for Explain
+	nodeScores := make(map[SymbolKey]float64, len(nodeByFile))
+	personalization := make(map[SymbolKey]float64, len(nodeByFile))
+	for f, node := range nodeByFile {
+		nodeScores[SymbolKey{FileName: f}] = pr[node.ID()]
+		personalization[SymbolKey{FileName: f}] = personal[node.ID()]
+	}
+	traced := make(edgeTrace)
+	traced.addFileEdges(edges)
+	r.traceRanking(allTags, mentionedFnames, mentionedIdents, nodeScores, personalization, traced, relevance, defRankSlice)
+
 	chatRelFnames := make(map[string]bool)
 	// If you had a slice of chatFnames, for example:
 	/*
@@ -621,13 +853,8 @@ func (r *RepoMap) getRankedTagsByPageRank(allTags []Tag, mentionedFnames, mentio
 			chatRelFnames[rel] = true
 		}
 	*/
-	if r.verbose {
-		fmt.Printf("\n\n## Ranked defs (SORTED):")
-		for _, v := range defRankSlice {
-			fmt.Printf("\n- %v / %s / %s", v.rank, v.fname, v.symbol)
-		}
-
-		fmt.Printf("\n\n")
+	for _, v := range defRankSlice {
+		log.Trace().Float64("rank", v.rank).Str("file", v.fname).Str("symbol", v.symbol).Msg("ranked definition")
 	}
 
 	//--------------------------------------------------------
@@ -680,9 +907,9 @@ func toDefRankSlice(edgeRanks map[EdgeRank]float64) []DefRank {
 	return defRankSlice
 }
 
-// distributeRank inspects each node's PageRank, sums the weights of all its out-edges,
-// and then distributes that node's rank proportionally along those edges.
-// The result is a mapping (defFile, symbol) -> rank. This parallels:
+// distributeRank inspects each node's PageRank, sums the weights of its out-edges
+// ranking a definition, and then distributes that node's rank proportionally along
+// those edges. The result is a mapping (defFile, symbol) -> rank. This parallels:
 //
 //	for src in G.nodes:
 //	    srcRank = ranked[src]
@@ -690,72 +917,40 @@ func toDefRankSlice(edgeRanks map[EdgeRank]float64) []DefRank {
 //	    for edge in out-edges:
 //	        portion = srcRank * (edgeWeight / totalWeight)
 //	        ranked_definitions[(edge.target, edge.symbol)] += portion
-func distributeRank(
-	pr map[int64]float64,
-	defines map[string]map[string]struct{},
-	references map[string][]string,
-	nodeByFile map[string]graph.Node,
-	mentionedIdents map[string]bool,
-) map[EdgeRank]float64 {
+func distributeRank(pr map[int64]float64, edges []fileEdge, nodeByFile map[string]graph.Node) map[EdgeRank]float64 {
+	totals := make(map[string]float64)
+	for _, e := range edges {
+		if e.symbol != "" {
+			totals[e.src] += e.weight
+		}
+	}
 
-	// 6) Distribute rank from each src node across its out edges
 	edgeRanks := make(map[EdgeRank]float64)
-
-	for symbol, refMap := range references {
-		defFiles := defines[symbol]
-		if defFiles == nil {
+	for _, e := range edges {
+		if e.symbol == "" {
 			continue
 		}
-
-		// // ndelorme - ranked tags
-		// fmt.Println("\n\nRanked tags:")
-		// for _, t := range rankedTags {
-		// 	fmt.Printf("- %s / %d / %s\n", t.Kind, t.Line, t.Name)
-		// }
-
-		var mul float64
-		switch {
-		case mentionedIdents[symbol]:
-			mul = 10.0
-		case strings.HasPrefix(symbol, "_"):
-			mul = 0.1
-		default:
-			mul = 1.0
-		}
-
-		for _, refFile := range refMap {
-			w := mul * math.Sqrt(float64(len(refMap)))
-			sumW := float64(len(defFiles)) * w // If each defFile gets w from refFile
-
-			srcRank := pr[nodeByFile[refFile].ID()]
-			if sumW == 0 {
-				continue
-			}
-			for defFile := range defFiles {
-				portion := srcRank * (w / sumW)
-				edgeRanks[struct {
-					dst    string
-					symbol string
-				}{dst: defFile, symbol: symbol}] += portion
-			}
-		}
+		portion := pr[nodeByFile[e.src].ID()] * e.weight / totals[e.src]
+		edgeRanks[EdgeRank{dst: e.dst, symbol: e.symbol}] += portion
 	}
-
 	return edgeRanks
 }
 
 // buildFileGraph scans the union of (defines, references) to find all unique filenames
 // and create a node for each. The return is a MultiDirectedGraph plus a lookup map to
-// find that node by filename.
+// find that node by filename, and the edges of the graph: a reference edge per
+// reference to a definition, and the non-identifier edges.
 func (r *RepoMap) buildFileGraph(
 	defines map[string]map[string]struct{},
 	references map[string][]string,
 	identifiers map[string]bool,
+	infos map[string]symbolInfo,
 	mentionedIdents map[string]bool,
 ) (
 	g *multi.WeightedDirectedGraph,
 	nodeByFile map[string]graph.Node,
 	fileSet map[string]struct{},
+	edges []fileEdge,
 ) {
 	// 2) Build a multi directed graph
 	g = multi.NewWeightedDirectedGraph()
@@ -776,6 +971,11 @@ func (r *RepoMap) buildFileGraph(
 		}
 	}
 
+	for _, e := range r.fileEdges {
+		fileSet[e.src] = struct{}{}
+		fileSet[e.dst] = struct{}{}
+	}
+
 	// Create node for each file
 	for f := range fileSet {
 		n := g.NewNode()
@@ -783,79 +983,81 @@ func (r *RepoMap) buildFileGraph(
 		nodeByFile[f] = n
 	}
 
-	if r.verbose {
-		fmt.Printf("\n\nNumber of nodes (files): %d\n", g.Nodes().Len())
-	}
+	log.Trace().Int("nodes", g.Nodes().Len()).Msg("file graph")
 
 	// 3) For each ident, link referencing file -> defining file with weight
+	cfg := r.ranking()
 	for ident := range identifiers {
 		defFiles := defines[ident]
 		if len(defFiles) == 0 {
 			continue
 		}
 
-		var mul float64
-		switch {
-		case mentionedIdents[ident]:
-			mul = 10.0
-		case strings.HasPrefix(ident, "_"):
-			mul = 0.1
-		default:
-			mul = 1.0
-		}
+		w := cfg.referenceEdgeWeight(ident, infos[ident], len(references[ident]), mentionedIdents)
 
 		for _, refFile := range references[ident] {
-			// log.Trace().Msg(color.YellowString("refFile: %s, numRefs: %d"), refFile, numRefs))
-			w := mul * math.Sqrt(float64(len(references[ident])))
-			for defFile := range defFiles {
-				refNode := nodeByFile[refFile]
-				defNode := nodeByFile[defFile]
-
-				// Create a weighted edge
-				edge := g.NewWeightedLine(refNode, defNode, w)
-				g.SetWeightedLine(edge)
+			for _, defFile := range r.deps.Targets(refFile, defFiles) {
+				// Boosted by the history of the defining file
+				edges = append(edges, fileEdge{src: refFile, dst: defFile, weight: w * r.historyBoost(defFile), kind: "reference", symbol: ident})
 			}
 		}
 	}
 
-	return g, nodeByFile, fileSet
+	// 4) Link files through non-identifier edges, eg. imports
+	for _, e := range r.fileEdges {
+		if e.src != e.dst {
+			edges = append(edges, e)
+		}
+	}
+
+	var kept []fileEdge
+	for _, e := range edges {
+		if e.weight <= 0 {
+			continue
+		}
+		g.SetWeightedLine(g.NewWeightedLine(nodeByFile[e.src], nodeByFile[e.dst], e.weight))
+		kept = append(kept, e)
+	}
+	return g, nodeByFile, fileSet, kept
 }
 
 // buildReferenceMaps reads a slice of Tag objects and partitions them into
 // (symbol -> set of files that define it) and (symbol -> map[file] countOfRefs).
 // It also tracks the actual definition Tag objects for (file,symbol).
 func (r *RepoMap) buildReferenceMaps(allTags []Tag) (
-	defines map[string]map[string]struct{}, // symbol -> set{relFname}
-	references map[string][]string, // symbol -> map[relFname] -> # of references
-	definitions map[tagKey][]Tag, // (relFname, symbol) -> slices of definition tags
+	defines map[string]map[string]struct{}, // qualified symbol -> set{relFname}
+	references map[string][]string, // qualified symbol -> map[relFname] -> # of references
+	definitions map[tagKey][]Tag, // (relFname, qualified symbol) -> slices of definition tags
 	identifiers map[string]bool, // set of symbols that have both defines and references
 ) {
 	// 1) Collect references, definitions
-	// defines is a set of filenames that define a symbol
-	defines = make(map[string]map[string]struct{}) // symbol -> set of filenames that define it
 	// references is a list of files per symbol
 	references = make(map[string][]string) // symbol -> map of (referencerFile -> countOfRefs)
 	// definitions is a set of symbols (tags) including file where they are defined
 	definitions = make(map[tagKey][]Tag) // (fname, symbol) -> slice of definition Tags
 
-	for _, t := range allTags {
-		rel := r.GetRelFname(t.FilePath)
+	// Definitions are keyed by their qualified name so that eg. Server.Close
+	// and Client.Close remain distinct symbols. qualifiedByName indexes those
+	// qualified names by bare name to resolve references.
+	defines, qualifiedByName := r.indexDefinitions(allTags)
 
-		switch t.Kind {
-		case TagKindDef:
-			if defines[t.Name] == nil {
-				defines[t.Name] = make(map[string]struct{})
-			}
-			defines[t.Name][rel] = struct{}{}
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		k := tagKey{fname: r.GetRelFname(t.FilePath), symbol: t.QualifiedName()}
+		definitions[k] = append(definitions[k], t)
+	}
 
-			k := tagKey{fname: rel, symbol: t.Name}
-			definitions[k] = append(definitions[k], t)
+	for _, t := range allTags {
+		if t.Kind != TagKindRef {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
 
-		case TagKindRef:
-			// if references[t.Name] == nil {
-			// 	references[t.Name] = map[string][]string{t.FileName: {rel}}
-			// }
-			references[t.Name] = append(references[t.Name], rel)
+		symbols := resolveReference(t, qualifiedByName[t.Name])
+		for _, symbol := range narrowByImports(r.deps, rel, symbols, defines) {
+			references[symbol] = append(references[symbol], rel)
 		}
 	}
 
@@ -881,6 +1083,85 @@ func (r *RepoMap) buildReferenceMaps(allTags []Tag) (
 	return defines, references, definitions, identifiers
 }
 
+// indexDefinitions returns the set of files defining each qualified symbol,
+// and the qualified symbols sharing each bare name.
+func (r *RepoMap) indexDefinitions(allTags []Tag) (defines map[string]map[string]struct{}, qualifiedByName map[string][]string) {
+	defines = make(map[string]map[string]struct{})
+	qualifiedByName = make(map[string][]string)
+
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		symbol := t.QualifiedName()
+		if defines[symbol] == nil {
+			defines[symbol] = make(map[string]struct{})
+			qualifiedByName[t.Name] = append(qualifiedByName[t.Name], symbol)
+		}
+		defines[symbol][r.GetRelFname(t.FilePath)] = struct{}{}
+	}
+	return defines, qualifiedByName
+}
+
+// resolveReference returns the qualified definition symbols a reference tag
+// points to. A qualified reference (eg. Server.Close or http.Get) only points
+// to definitions whose qualified name ends with it, when there are any. Other
+// references point to every definition sharing their bare name.
+func resolveReference(t Tag, candidates []string) []string {
+	if len(candidates) == 0 {
+		return []string{t.Name}
+	}
+	if len(t.Scope) == 0 {
+		return candidates
+	}
+
+	qualified := t.QualifiedName()
+	var matched []string
+	for _, c := range candidates {
+		if c == qualified || strings.HasSuffix(c, "."+qualified) {
+			matched = append(matched, c)
+		}
+	}
+	if len(matched) == 0 {
+		return candidates
+	}
+	return matched
+}
+
+// narrowByImports keeps the symbols a reference may point to that are
+// defined in files targeted by refFile per the import graph, eg. the User
+// class it imports rather than a namesake in another package.
+func narrowByImports(deps *DependencyGraph, refFile string, symbols []string, defines map[string]map[string]struct{}) []string {
+	if deps == nil || len(symbols) < 2 {
+		return symbols
+	}
+
+	defFiles := make(map[string]struct{})
+	for _, symbol := range symbols {
+		for f := range defines[symbol] {
+			defFiles[f] = struct{}{}
+		}
+	}
+	targets := make(map[string]struct{})
+	for _, f := range deps.Targets(refFile, defFiles) {
+		targets[f] = struct{}{}
+	}
+
+	var narrowed []string
+	for _, symbol := range symbols {
+		for f := range defines[symbol] {
+			if _, ok := targets[f]; ok {
+				narrowed = append(narrowed, symbol)
+				break
+			}
+		}
+	}
+	if len(narrowed) == 0 {
+		return symbols
+	}
+	return narrowed
+}
+
 // fallbackReferences is used when no references are found. Python code sets references = defines,
 // effectively giving each symbol a trivial reference from its own definer.
 func (r *RepoMap) fallbackReferences(defines map[string]map[string]struct{}) map[string]map[string]int {
@@ -895,7 +1176,33 @@ func (r *RepoMap) fallbackReferences(defines map[string]map[string]struct{}) map
 	return refs
 }
 
+// getFileEdges gathers the enabled non-identifier edges between files.
+func (r *RepoMap) getFileEdges(allFnames []string, allTags []Tag) []fileEdge {
+	var edges []fileEdge
+
+	r.deps = nil
+	if r.importWeight > 0 {
+		r.deps = r.GetDependencyGraph(allFnames)
+		linkDeclarations(r.deps, allTags)
+		edges = append(edges, r.getImportEdges(r.deps)...)
+	}
+
+	r.relations = nil
+	if r.relationWeight > 0 {
+		r.relations = r.getRelations(allFnames, allTags)
+		edges = append(edges, r.getRelationEdges(allTags)...)
+	}
+
+	if r.coChangeWeight > 0 {
+		edges = append(edges, r.getCoChangeEdges(allFnames)...)
+	}
+
+	return edges
+}
+
 // GetRankedTagsMap orchestrates calls to getRankedTags and toTree to produce the final “map” string.
+// The map holds the best ranked definitions whose tree fits in maxMapTokens, or all of them when
+// maxMapTokens is zero or less.
 func (r *RepoMap) GetRankedTagsMap(
 	chatFnames, otherFnames []string,
 	maxMapTokens int,
@@ -907,16 +1214,37 @@ func (r *RepoMap) GetRankedTagsMap(
 	// Combine chatFnames and otherFnames into a map of unique elements
 	allFnames := uniqueElements(chatFnames, otherFnames)
 
-	// Collect all tags from those files
-	allTags := r.getTagsFromFiles(allFnames, commonWords)
+	// Collect all tags from those files, unless already parsed
+	allTags := r.parsedTags
+	if allTags == nil {
+		allTags = r.getTagsFromFiles(allFnames, commonWords)
+	}
+
+	// Read the git history signals, if enabled, before the co-change edges
+	// mined from the same git log
+	r.history = r.loadHistory()
+
+	// Collect the edges between files that do not come from identifiers
+	r.fileEdges = r.getFileEdges(allFnames, allTags)
+
+	// The files the directory proximity is measured from, if enabled
+	r.proximityAnchors = r.proximityAnchorSet(chatFnames, mentionedFnames)
+
+	// Forget the previous ranking, see Explain
+	r.lastRanking = nil
 
 	// Handle empty tag list
 	if len(allTags) == 0 {
 		return ""
 	}
 
-	// Get ranked tags by PageRank
-	rankedTags := r.getRankedTagsByPageRank(allTags, mentionedFnames, mentionedIdents)
+	// Get ranked tags by PageRank, over files or over definitions
+	var rankedTags []Tag
+	if r.symbolRanking {
+		rankedTags = r.getRankedTagsBySymbolRank(allTags, mentionedFnames, mentionedIdents)
+	} else {
+		rankedTags = r.getRankedTagsByPageRank(allTags, mentionedFnames, mentionedIdents)
+	}
 
 	// special := filterImportantFiles(otherFnames)
 
@@ -927,40 +1255,11 @@ func (r *RepoMap) GetRankedTagsMap(
 	// }
 	// finalTags := append(specialTags, rankedTags...)
 
-	finalTags := rankedTags
-
-	bestTree := ""
-	// bestTreeTokens := 0.0
-
-	// lb := 0
-	ub := len(finalTags)
-	middle := ub
-	if middle > 30 {
-		middle = 30
-	}
-
-	bestTree = r.toTree(finalTags, chatFnames)
+	// Show the extra lines of interest first, eg. stack trace frames
+	finalTags := append(r.linesOfInterestTags(), rankedTags...)
 
-	// for lb <= ub {
-	// 	tree := r.toTree(finalTags[:middle], chatFnames)
-	// 	numTokens := r.TokenCount(tree)
-
-	// 	diff := math.Abs(numTokens - float64(maxMapTokens))
-	// 	pctErr := diff / float64(maxMapTokens)
-	// 	if (numTokens <= float64(maxMapTokens) && numTokens > bestTreeTokens) || pctErr < 0.15 {
-	// 		bestTree = tree
-	// 		bestTreeTokens = numTokens
-	// 		if pctErr < 0.15 {
-	// 			break
-	// 		}
-	// 	}
-	// 	if numTokens < float64(maxMapTokens) {
-	// 		lb = middle + 1
-	// 	} else {
-	// 		ub = middle - 1
-	// 	}
-	// 	middle = (lb + ub) / 2
-	// }
+	bestTree, shown := r.fitToBudget(finalTags, chatFnames, maxMapTokens)
+	r.traceBudget(finalTags[:shown], maxMapTokens)
 
 	endTime := time.Now()
 	r.totalProcessingTime = endTime.Sub(startTime).Seconds()
@@ -1042,6 +1341,54 @@ func (r *RepoMap) Generate(
 	return repoContent
 }
 
+// fitToBudget renders the longest prefix of the ranked tags whose tree fits
+// in maxMapTokens, found by binary search, or all of them when
+// maxMapTokens is zero or less. It returns the tree and the length of the
+// prefix.
+func (r *RepoMap) fitToBudget(tags []Tag, chatFnames []string, maxMapTokens int) (string, int) {
+	tree := r.toTree(tags, chatFnames)
+	if maxMapTokens <= 0 || r.TokenCount(tree) <= float64(maxMapTokens) {
+		return tree, len(tags)
+	}
+
+	bestTree, bestLen := "", 0
+	lb, ub := 0, len(tags)-1
+	for lb <= ub {
+		middle := (lb + ub) / 2
+		tree := r.toTree(tags[:middle], chatFnames)
+		if r.TokenCount(tree) <= float64(maxMapTokens) {
+			bestTree, bestLen = tree, middle
+			lb = middle + 1
+		} else {
+			ub = middle - 1
+		}
+	}
+	return bestTree, bestLen
+}
+
+// linesOfInterestTags returns a tag for each extra line of interest, sorted
+// by file and line.
+func (r *RepoMap) linesOfInterestTags() []Tag {
+	var tags []Tag
+	for rel, lines := range r.linesOfInterest {
+		for _, line := range lines {
+			tags = append(tags, Tag{
+				FileName: rel,
+				FilePath: filepath.Join(r.root, rel),
+				Line:     line,
+				EndLine:  line,
+			})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].FileName != tags[j].FileName {
+			return tags[i].FileName < tags[j].FileName
+		}
+		return tags[i].Line < tags[j].Line
+	})
+	return tags
+}
+
 // toTree converts a list of Tag objects into a tree-like string representation.
 func (r *RepoMap) toTree(tags []Tag, chatFnames []string) string {
 	// Return immediately if no tags
@@ -1064,6 +1411,9 @@ func (r *RepoMap) toTree(tags []Tag, chatFnames []string) string {
 	//  2) Sort the tags first by FileName in ascending order, and then by Line in ascending order
 	// if two tags have the same FileName. This ensures a stable order where entries
 	// are grouped by file and appear sequentially by their line numbers within each file.
+	// Work on a copy: the caller's ranked order is kept, and the sentinel appended below
+	// must not overwrite the tag following a prefix of it.
+	tags = append([]Tag(nil), tags...)
 	sort.Slice(tags, func(i, j int) bool {
 		if tags[i].FileName != tags[j].FileName {
 			return tags[i].FileName < tags[j].FileName
@@ -1155,6 +1505,17 @@ func (r *RepoMap) renderTree(relFname string, code []byte, linesOfInterest []int
 		fmt.Printf("\nrender_tree:  %s, %v\n", relFname, linesOfInterest)
 	}
 
+	padding := 2
+
+	// grep-ast cannot parse the extensions germ maps itself, eg. C, C++ or .sh
+	if _, bundled := extraExtensions[strings.ToLower(filepath.Ext(relFname))]; bundled {
+		lang, _, err := getLanguageFromFileName(relFname, code)
+		if err != nil {
+			return "", err
+		}
+		return renderScopes(lang, code, linesOfInterest, padding)
+	}
+
 	// Build a grep-ast TreeContext.
 	tc, err := grepast.NewTreeContext(
 		relFname, code,
@@ -1163,7 +1524,7 @@ func (r *RepoMap) renderTree(relFname string, code []byte, linesOfInterest []int
 		grepast.WithLastLineContext(false),
 		grepast.WithTopMargin(0),
 		grepast.WithLinesOfInterestMarked(false),
-		grepast.WithLinesOfInterestPadding(2),
+		grepast.WithLinesOfInterestPadding(padding),
 		grepast.WithTopOfFileParentScope(false),
 	)
 	if err != nil {
@@ -7,7 +7,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	sitter "github.com/tree-sitter/go-tree-sitter"
+	sitter_css "github.com/tree-sitter/tree-sitter-css/bindings/go"
 	sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	sitter_html "github.com/tree-sitter/tree-sitter-html/bindings/go"
+	sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
 )
 
 // TestNewRepoMap tests the NewRepoMap function.
@@ -190,6 +193,138 @@ func TestGetTagsFromQueryCapture(t *testing.T) {
 	})
 }
 
+// getTestTags parses sourceCode with the embedded query for langID.
+func getTestTags(t *testing.T, lang *sitter.Language, langID string, sourceCode []byte) []Tag {
+	t.Helper()
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(lang)
+	tree := parser.Parse(sourceCode, nil)
+
+	r := &RepoMap{}
+	q, err := r.LoadQuery(lang, langID)
+	if err != nil {
+		t.Fatalf("Failed to load %s query: %v", langID, err)
+	}
+	defer q.Close()
+
+	return getTagsFromQueryCapture("rel/"+langID, "/absolute/"+langID, langID, q, tree, sourceCode, nil, nil)
+}
+
+// findTag returns the first tag matching kind and name, or nil.
+func findTag(tags []Tag, kind, name string) *Tag {
+	for i := range tags {
+		if tags[i].Kind == kind && tags[i].Name == name {
+			return &tags[i]
+		}
+	}
+	return nil
+}
+
+// TestWebTags verifies the embedded CSS, HTML and JavaScript queries link
+// class definitions in stylesheets to their usages in markup and JSX, and
+// custom elements to their definitions.
+func TestWebTags(t *testing.T) {
+	css := getTestTags(t, sitter.NewLanguage(sitter_css.Language()), "css", []byte(`
+:root { --main-color: red; }
+.btn-primary { color: var(--main-color); animation: spin-around 1s; }
+#header { color: blue; }
+@keyframes spin-around { from { top: 0 } }
+`))
+
+	html := getTestTags(t, sitter.NewLanguage(sitter_html.Language()), "html", []byte(`
+<div id="main-panel" class="btn-primary card-body">hi</div>
+<user-profile></user-profile>
+`))
+
+	js := getTestTags(t, sitter.NewLanguage(sitter_javascript.Language()), "javascript", []byte(`
+const Card = () => <div className="btn-primary card-body">hi</div>;
+customElements.define("user-profile", UserProfile);
+`))
+
+	assert.NotNil(t, findTag(css, TagKindDef, "btn-primary"), "Expected CSS class definition")
+	assert.NotNil(t, findTag(css, TagKindDef, "header"), "Expected CSS id definition")
+	assert.NotNil(t, findTag(css, TagKindDef, "--main-color"), "Expected CSS custom property definition")
+	assert.NotNil(t, findTag(css, TagKindRef, "--main-color"), "Expected CSS custom property reference")
+	assert.NotNil(t, findTag(css, TagKindDef, "spin-around"), "Expected CSS keyframes definition")
+	assert.NotNil(t, findTag(css, TagKindRef, "spin-around"), "Expected CSS keyframes reference")
+
+	assert.NotNil(t, findTag(html, TagKindDef, "main-panel"), "Expected HTML id definition")
+	assert.NotNil(t, findTag(html, TagKindRef, "btn-primary"), "Expected first HTML class reference")
+	assert.NotNil(t, findTag(html, TagKindRef, "card-body"), "Expected second HTML class reference")
+	assert.NotNil(t, findTag(html, TagKindRef, "user-profile"), "Expected HTML custom element reference")
+
+	assert.NotNil(t, findTag(js, TagKindRef, "btn-primary"), "Expected first JSX className reference")
+	assert.NotNil(t, findTag(js, TagKindRef, "card-body"), "Expected second JSX className reference")
+	if tg := findTag(js, TagKindDef, "user-profile"); assert.NotNil(t, tg, "Expected custom element definition") {
+		assert.Equal(t, "element", tg.SubKind)
+	}
+}
+
+// TestGoTags verifies the embedded Go query captures package-level values,
+// struct fields, interface methods, embedded types and imports.
+func TestGoTags(t *testing.T) {
+	tags := getTestTags(t, sitter.NewLanguage(sitter_go.Language()), "go", []byte(`package srv
+
+import (
+	"github.com/rs/zerolog/log"
+	yml "gopkg.in/yaml.v3"
+	"github.com/jackc/pgx/v5"
+	_ "embed"
+)
+
+const MaxConns = 10
+
+var ErrClosed = errors.New("closed")
+
+type Config struct {
+	Timeout int
+	*log.Logger
+}
+
+type Closer interface {
+	Close() error
+}
+
+func run(c Config) {
+	local := c.Timeout
+	Dial(local)
+}
+`))
+
+	tests := []struct {
+		kind    string
+		name    string
+		subKind string
+	}{
+		{TagKindRef, "log", "import"},
+		{TagKindRef, "yml", "import"},
+		{TagKindRef, "pgx", "import"},
+		{TagKindDef, "MaxConns", "constant"},
+		{TagKindDef, "ErrClosed", "variable"},
+		{TagKindDef, "Config", "type"},
+		{TagKindDef, "Timeout", "field"},
+		{TagKindRef, "Logger", "embed"},
+		{TagKindDef, "Closer", "interface"},
+		{TagKindDef, "Close", "method"},
+		{TagKindDef, "run", "function"},
+		{TagKindRef, "Timeout", "field"},
+		{TagKindRef, "Dial", "call"},
+	}
+
+	for _, tt := range tests {
+		tg := findTag(tags, tt.kind, tt.name)
+		if assert.NotNil(t, tg, "Expected %s tag for %s", tt.kind, tt.name) {
+			assert.Equal(t, tt.subKind, tg.SubKind, "Unexpected sub kind for %s", tt.name)
+		}
+	}
+	assert.Nil(t, findTag(tags, TagKindRef, "yaml"), "Expected aliased import to be tagged by its alias")
+	assert.Nil(t, findTag(tags, TagKindRef, "embed"), "Expected blank import not to be tagged")
+
+	// Function-local variables are not package-level definitions.
+	assert.Nil(t, findTag(tags, TagKindDef, "local"), "Expected no definition for a local variable")
+}
+
 // TestGetRankedTagsByPageRank contains multiple sub-tests demonstrating how you might
 // set up scenarios and verify outcomes. Each sub-test defines a small collection of
 // tags, “mentioned” data, and checks the ranked output.
@@ -201,8 +336,8 @@ func TestGetRankedTagsByPageRank(t *testing.T) {
 		r := &RepoMap{}
 
 		allTags := []Tag{
-			{"FileA.go", "path/to/FileA.go", 10, "Foo", TagKindDef},
-			{"FileB.go", "path/to/FileB.go", 20, "Bar", TagKindDef},
+			{FileName: "FileA.go", FilePath: "path/to/FileA.go", Line: 10, Name: "Foo", Kind: TagKindDef},
+			{FileName: "FileB.go", FilePath: "path/to/FileB.go", Line: 20, Name: "Bar", Kind: TagKindDef},
 		}
 
 		mentionedFnames := map[string]bool{}
@@ -232,8 +367,8 @@ func TestGetRankedTagsByPageRank(t *testing.T) {
 		r := &RepoMap{}
 
 		allTags := []Tag{
-			{"FileA.go", "path/to/FileA.go", 10, "Foo", TagKindDef},
-			{"FileB.go", "path/to/FileB.go", 20, "Foo", TagKindRef},
+			{FileName: "FileA.go", FilePath: "path/to/FileA.go", Line: 10, Name: "Foo", Kind: TagKindDef},
+			{FileName: "FileB.go", FilePath: "path/to/FileB.go", Line: 20, Name: "Foo", Kind: TagKindRef},
 		}
 
 		// Nothing “mentioned” in chat
@@ -263,9 +398,9 @@ func TestGetRankedTagsByPageRank(t *testing.T) {
 		r := &RepoMap{}
 
 		allTags := []Tag{
-			{"FileA.go", "path/to/FileA.go", 10, "Foo", TagKindDef},
-			{"FileB.go", "path/to/FileB.go", 20, "Foo", TagKindDef},
-			{"FileC.go", "path/to/FileC.go", 30, "Foo", TagKindRef},
+			{FileName: "FileA.go", FilePath: "path/to/FileA.go", Line: 10, Name: "Foo", Kind: TagKindDef},
+			{FileName: "FileB.go", FilePath: "path/to/FileB.go", Line: 20, Name: "Foo", Kind: TagKindDef},
+			{FileName: "FileC.go", FilePath: "path/to/FileC.go", Line: 30, Name: "Foo", Kind: TagKindRef},
 		}
 
 		mentionedFnames := map[string]bool{
@@ -398,3 +533,41 @@ func Demo() {
 	// 	}
 	// })
 }
+
+// TestFitToBudget verifies the map keeps the best ranked tags fitting in the
+// token budget.
+func TestFitToBudget(t *testing.T) {
+	root := t.TempDir()
+	files := make(map[string]string)
+	for _, name := range []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot"} {
+		files[name+".go"] = "package app\n\nfunc " + name + "Handler() {\n" + strings.Repeat("\t// filler\n", 20) + "}\n"
+	}
+	fnames := writeTestFiles(t, root, files)
+
+	r := NewRepoMap(root, nil)
+	tags := r.getTagsFromFiles(fnames, commonWords)
+
+	full, n := r.fitToBudget(tags, nil, 0)
+	assert.Equal(t, len(tags), n)
+	fitted, n := r.fitToBudget(tags, nil, 100)
+	assert.LessOrEqual(t, r.TokenCount(fitted), 100.0)
+	assert.NotEmpty(t, fitted)
+	assert.Less(t, len(fitted), len(full))
+	assert.Equal(t, r.toTree(tags[:n], nil), fitted)
+
+	unbounded, _ := r.fitToBudget(tags, nil, 100000)
+	assert.Equal(t, full, unbounded)
+
+	// Generate trims the map to the max map tokens, which are not scaled up
+	// with a chat file
+	r = NewRepoMap(root, nil, WithMaxTokens(100000))
+	r.Generate(fnames[:1], fnames[1:], nil, nil)
+	all := r.lastMap
+
+	r = NewRepoMap(root, nil, WithMaxTokens(100))
+	out := r.Generate(fnames[:1], fnames[1:], nil, nil)
+	assert.LessOrEqual(t, r.TokenCount(r.lastMap), 100.0)
+	assert.NotEmpty(t, r.lastMap)
+	assert.Contains(t, out, r.lastMap)
+	assert.Less(t, len(r.lastMap), len(all))
+}
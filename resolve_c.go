@@ -0,0 +1,286 @@
+package germ
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// compileCommandsLocations are the paths, relative to the root, searched for a
+// compilation database when none is configured.
+var compileCommandsLocations = []string{"compile_commands.json", "build/compile_commands.json"}
+
+// cHeaderExtensions are the extensions of C and C++ header files.
+var cHeaderExtensions = map[string]struct{}{
+	".h": {}, ".hh": {}, ".hpp": {}, ".hxx": {},
+}
+
+// compileCommand is an entry of a compile_commands.json compilation database.
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Command   string   `json:"command"`
+	Arguments []string `json:"arguments"`
+}
+
+// cIncludeResolver resolves #include directives with the include search paths
+// of each file in the compilation database, or the configured include
+// directories for files it does not list, eg. headers.
+type cIncludeResolver struct {
+	idx *fileIndex
+	// quote and search map files to their -iquote and -I/-isystem directories
+	quote  map[string][]string
+	search map[string][]string
+	// fallback are the directories searched for files absent from the database
+	fallback []string
+}
+
+// newCIncludeResolver reads the compilation database at compileCommands, or
+// at a default location, and appends includeDirs to every search path.
+func newCIncludeResolver(idx *fileIndex, compileCommands string, includeDirs []string) *cIncludeResolver {
+	res := &cIncludeResolver{
+		idx:    idx,
+		quote:  make(map[string][]string),
+		search: make(map[string][]string),
+	}
+
+	var extra []string
+	for _, dir := range includeDirs {
+		if rel, ok := res.relDir(idx.root, dir); ok {
+			extra = append(extra, rel)
+		}
+	}
+
+	for _, cmd := range readCompileCommands(idx.root, compileCommands) {
+		file := cmd.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(cmd.Directory, file)
+		}
+		rel, err := filepath.Rel(idx.root, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		args := cmd.Arguments
+		if len(args) == 0 {
+			args = splitCommandLine(cmd.Command)
+		}
+		quote, search := includeFlags(args)
+
+		for _, dir := range quote {
+			if d, ok := res.relDir(cmd.Directory, dir); ok {
+				res.quote[rel] = appendUnique(res.quote[rel], d)
+			}
+		}
+		for _, dir := range search {
+			if d, ok := res.relDir(cmd.Directory, dir); ok {
+				res.search[rel] = appendUnique(res.search[rel], d)
+				res.fallback = appendUnique(res.fallback, d)
+			}
+		}
+	}
+
+	for rel := range res.search {
+		for _, d := range extra {
+			res.search[rel] = appendUnique(res.search[rel], d)
+		}
+	}
+	for _, d := range extra {
+		res.fallback = appendUnique(res.fallback, d)
+	}
+
+	return res
+}
+
+// relDir returns dir, relative to base when not absolute, as a directory
+// relative to the repository root. Directories outside the root are skipped.
+func (res *cIncludeResolver) relDir(base, dir string) (string, bool) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	rel, err := filepath.Rel(res.idx.root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// Resolve returns the file of an include directive. Quoted includes are
+// searched in the including file's directory, its -iquote directories, then
+// like system includes in its -I and -isystem directories, then the root.
+func (res *cIncludeResolver) Resolve(imp Import) []string {
+	p := filepath.FromSlash(strings.Trim(imp.Path, "<>"))
+
+	var dirs []string
+	if imp.Kind == "include" {
+		dirs = append(dirs, filepath.Dir(imp.FileName))
+		dirs = append(dirs, res.quote[imp.FileName]...)
+	}
+	if search, ok := res.search[imp.FileName]; ok {
+		dirs = append(dirs, search...)
+	} else {
+		dirs = append(dirs, res.fallback...)
+	}
+	if imp.Kind == "include" {
+		dirs = append(dirs, ".")
+	}
+
+	for _, dir := range dirs {
+		if dsts := res.idx.first(filepath.Join(dir, p)); len(dsts) > 0 {
+			return dsts
+		}
+	}
+	return nil
+}
+
+// readCompileCommands parses the compilation database at path, relative to
+// root, or at the first default location found. A missing or invalid
+// database yields no commands.
+func readCompileCommands(root, path string) []compileCommand {
+	candidates := compileCommandsLocations
+	if path != "" {
+		candidates = []string{path}
+	}
+
+	for _, c := range candidates {
+		if !filepath.IsAbs(c) {
+			c = filepath.Join(root, c)
+		}
+		data, err := os.ReadFile(c)
+		if err != nil {
+			continue
+		}
+		var cmds []compileCommand
+		if err := json.Unmarshal(data, &cmds); err != nil {
+			return nil
+		}
+		return cmds
+	}
+	return nil
+}
+
+// includeFlags returns the -iquote directories, and the -I, -isystem and
+// -idirafter directories in command line order.
+func includeFlags(args []string) (quote, search []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		for _, flag := range []string{"-iquote", "-isystem", "-idirafter", "-I"} {
+			if !strings.HasPrefix(arg, flag) {
+				continue
+			}
+			dir := strings.TrimPrefix(arg, flag)
+			if dir == "" && i+1 < len(args) {
+				i++
+				dir = args[i]
+			}
+			if flag == "-iquote" {
+				quote = append(quote, dir)
+			} else {
+				search = append(search, dir)
+			}
+			break
+		}
+	}
+	return quote, search
+}
+
+// splitCommandLine splits a shell command line into arguments, honouring
+// quotes and backslash escapes.
+func splitCommandLine(cmd string) []string {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+
+	for i := 0; i < len(cmd); i++ {
+		c := rune(cmd[i])
+		switch {
+		case c == '\\' && quote != '\'' && i+1 < len(cmd):
+			i++
+			cur.WriteByte(cmd[i])
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// isCHeader reports whether fname is a C or C++ header.
+func isCHeader(fname string) bool {
+	_, ok := cHeaderExtensions[strings.ToLower(filepath.Ext(fname))]
+	return ok
+}
+
+// linkDeclarations records, for each C/C++ header, the source files that
+// define symbols the header declares. Sources including the header are
+// linked, or else those sharing its base name, eg. util.h and util.c.
+func linkDeclarations(deps *DependencyGraph, allTags []Tag) {
+	defines := make(map[string]map[string]struct{})
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		if _, ok := extraExtensions[strings.ToLower(filepath.Ext(t.FileName))]; !ok {
+			continue
+		}
+		name := t.QualifiedName()
+		if defines[name] == nil {
+			defines[name] = make(map[string]struct{})
+		}
+		defines[name][t.FileName] = struct{}{}
+	}
+
+	stem := func(f string) string { return strings.TrimSuffix(filepath.Base(f), filepath.Ext(f)) }
+
+	impls := make(map[string][]string)
+	for _, files := range defines {
+		for header := range files {
+			if !isCHeader(header) {
+				continue
+			}
+			var includers, namesakes []string
+			for src := range files {
+				switch {
+				case isCHeader(src):
+				case slices.Contains(deps.Files[src], header):
+					includers = append(includers, src)
+				case stem(src) == stem(header):
+					namesakes = append(namesakes, src)
+				}
+			}
+			if len(includers) == 0 {
+				includers = namesakes
+			}
+			for _, src := range includers {
+				impls[header] = appendUnique(impls[header], src)
+			}
+		}
+	}
+	for _, srcs := range impls {
+		sort.Strings(srcs)
+	}
+	deps.Implementations = impls
+}
@@ -0,0 +1,107 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCIncludeResolver verifies includes resolve with the search paths of the
+// compilation database, falling back to the configured include directories.
+func TestCIncludeResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"build/compile_commands.json": `[
+  {"directory": "` + filepath.Join(root, "build") + `", "file": "../src/main.c",
+   "command": "cc -iquote ../src/private -I ../include -I\"../third party\" -c ../src/main.c"},
+  {"directory": "` + root + `", "file": "src/io.c",
+   "arguments": ["cc", "-isystem", "vendor", "-c", "src/io.c"]}
+]`,
+	})
+
+	rels := []string{
+		"src/main.c",
+		"src/io.c",
+		"src/private/config.h",
+		"include/lib/api.h",
+		"third party/tp.h",
+		"vendor/v.h",
+		"extra/ext.h",
+		"src/local.h",
+	}
+	res := newCIncludeResolver(newFileIndex(root, rels), "", []string{"extra"})
+
+	tests := []struct {
+		name string
+		imp  Import
+		want []string
+	}{
+		{"same directory", Import{FileName: "src/main.c", Path: "local.h", Kind: "include"}, []string{"src/local.h"}},
+		{"iquote", Import{FileName: "src/main.c", Path: "config.h", Kind: "include"}, []string{"src/private/config.h"}},
+		{"include dir", Import{FileName: "src/main.c", Path: "<lib/api.h>", Kind: "system"}, []string{"include/lib/api.h"}},
+		{"quoted include dir", Import{FileName: "src/main.c", Path: "tp.h", Kind: "include"}, []string{"third party/tp.h"}},
+		{"isystem", Import{FileName: "src/io.c", Path: "<v.h>", Kind: "system"}, []string{"vendor/v.h"}},
+		{"not searched", Import{FileName: "src/io.c", Path: "<lib/api.h>", Kind: "system"}, nil},
+		{"configured", Import{FileName: "src/io.c", Path: "ext.h", Kind: "include"}, []string{"extra/ext.h"}},
+		{"header fallback", Import{FileName: "src/local.h", Path: "lib/api.h", Kind: "include"}, []string{"include/lib/api.h"}},
+		{"system", Import{FileName: "src/main.c", Path: "<stdio.h>", Kind: "system"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, res.Resolve(tt.imp))
+		})
+	}
+}
+
+// TestLinkDeclarations verifies headers are linked to the source files
+// defining what they declare, and references prefer those definitions.
+func TestLinkDeclarations(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"app/main.c":   "#include \"../lib/util.h\"\nint main(void) { return util(1); }\n",
+		"lib/util.h":   "int util(int x);\n",
+		"lib/util.c":   "#include \"util.h\"\nint util(int x) { return x; }\n",
+		"other/util.c": "int util(int x) { return -x; }\n",
+	})
+
+	r := &RepoMap{root: root, importWeight: 1}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	r.fileEdges = r.getFileEdges(fnames, allTags)
+
+	assert.Equal(t, map[string][]string{"lib/util.h": {"lib/util.c"}}, r.deps.Implementations)
+	assert.Contains(t, r.fileEdges, fileEdge{src: "lib/util.h", dst: "lib/util.c", weight: 1, kind: "declaration"})
+
+	var files []string
+	for _, tg := range r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{}) {
+		if tg.Name == "util" {
+			files = append(files, tg.FileName)
+		}
+	}
+	assert.ElementsMatch(t, []string{"lib/util.c", "lib/util.h"}, files)
+}
+
+// TestLinkDeclarationsCpp verifies C++ class and namespace members defined
+// out of line are linked to the header declaring them.
+func TestLinkDeclarationsCpp(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"lib/shape.h":    "namespace geo {\nclass Shape {\npublic:\n  void draw();\n};\nint area();\n}\n",
+		"lib/shape.cpp":  "#include \"shape.h\"\nnamespace geo {\nvoid Shape::draw() {}\n}\nint geo::area() { return 1; }\n",
+		"other/draw.cpp": "void draw() {}\nint area() { return 0; }\n",
+	})
+
+	r := &RepoMap{root: root, importWeight: 1}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	r.fileEdges = r.getFileEdges(fnames, allTags)
+
+	var scopes []string
+	for _, tg := range allTags {
+		if tg.FileName == "lib/shape.cpp" && tg.Kind == TagKindDef {
+			scopes = append(scopes, tg.QualifiedName())
+		}
+	}
+	assert.ElementsMatch(t, []string{"geo.Shape.draw", "geo.area"}, scopes)
+	assert.Equal(t, map[string][]string{"lib/shape.h": {"lib/shape.cpp"}}, r.deps.Implementations)
+}
@@ -0,0 +1,141 @@
+package germ
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// javaSourceRoots are the conventional Maven and Gradle source directories,
+// used to derive the package of files that do not declare one readably.
+var javaSourceRoots = []string{"src/main/java", "src/test/java"}
+
+// javaImportResolver resolves Java imports to the files declaring the imported
+// classes, keyed by fully qualified class name.
+type javaImportResolver struct {
+	idx *fileIndex
+	// classes maps fully qualified top-level class names to their file
+	classes map[string]string
+	// packages maps package names to the files they contain
+	packages map[string][]string
+}
+
+// newJavaImportResolver indexes the Java files of the repository by package,
+// as declared by each file or derived from its source root.
+func newJavaImportResolver(idx *fileIndex) *javaImportResolver {
+	res := &javaImportResolver{
+		idx:      idx,
+		classes:  make(map[string]string),
+		packages: make(map[string][]string),
+	}
+
+	for rel := range idx.files {
+		if filepath.Ext(rel) != ".java" {
+			continue
+		}
+		pkg, ok := readJavaPackage(filepath.Join(idx.root, rel))
+		if !ok {
+			pkg = javaLayoutPackage(rel)
+		}
+
+		class := strings.TrimSuffix(filepath.Base(rel), ".java")
+		if pkg != "" {
+			class = pkg + "." + class
+		}
+		res.classes[class] = rel
+		res.packages[pkg] = append(res.packages[pkg], rel)
+	}
+	for _, files := range res.packages {
+		sort.Strings(files)
+	}
+	return res
+}
+
+// readJavaPackage returns the package declared by a Java file, and whether the
+// file could be read. Files in the default package yield an empty name.
+// Comments, such as a license header or Javadoc, are skipped.
+func readJavaPackage(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	inComment := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inComment {
+			end := strings.Index(line, "*/")
+			if end < 0 {
+				continue
+			}
+			inComment = false
+			line = strings.TrimSpace(line[end+2:])
+		}
+		if strings.HasPrefix(line, "/*") {
+			end := strings.Index(line[2:], "*/")
+			if end < 0 {
+				inComment = true
+				continue
+			}
+			line = strings.TrimSpace(line[2+end+2:])
+		}
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "package ") {
+			return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";")), true
+		}
+		if strings.HasPrefix(line, "import ") || strings.Contains(line, "class ") || strings.Contains(line, "interface ") {
+			break
+		}
+	}
+	return "", true
+}
+
+// javaLayoutPackage derives the package of a file from its directory under a
+// conventional source root, eg. com.x for src/main/java/com/x/App.java.
+func javaLayoutPackage(rel string) string {
+	dir := filepath.ToSlash(filepath.Dir(rel))
+	for _, root := range javaSourceRoots {
+		if i := strings.Index(dir+"/", root+"/"); i >= 0 {
+			return strings.ReplaceAll(strings.Trim(dir[i+len(root):], "/"), "/", ".")
+		}
+	}
+	return ""
+}
+
+// Resolve returns the files declaring the classes of an import. Single type
+// imports name a class or a nested class, wildcard imports a package or a
+// class, and static imports a member of a class.
+func (res *javaImportResolver) Resolve(imp Import) []string {
+	name := imp.Path
+
+	switch imp.Kind {
+	case "wildcard":
+		if files, ok := res.packages[name]; ok {
+			return files
+		}
+	case "static":
+		// Drop the imported member
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[:i]
+		}
+	}
+
+	// Strip nested class names until a top-level class matches
+	for {
+		if f, ok := res.classes[name]; ok {
+			return []string{f}
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return nil
+		}
+		name = name[:i]
+	}
+}
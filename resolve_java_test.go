@@ -0,0 +1,84 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJavaImportResolver verifies single type, nested, wildcard and static
+// imports resolve to the files declaring the imported classes.
+func TestJavaImportResolver(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"core/src/main/java/com/x/model/User.java":   "package com.x.model;\n\npublic class User {\n  public static class Id {}\n}\n",
+		"core/src/main/java/com/x/model/Group.java":  "package com.x.model;\nclass Group {}\n",
+		"core/src/main/java/com/x/util/Strings.java": "/* license */\npackage com.x.util;\nclass Strings {}\n",
+		"legacy/User.java":                           "package com.y;\nclass User {}\n",
+		"legacy/Order.java":                          "/*\n * This class is part of the billing interface.\n */\n// interface Order\npackage com.y.orders;\nclass Order {}\n",
+		"app/src/test/java/com/x/AppTest.java":       "class AppTest {}\n",
+	}
+	writeTestFiles(t, root, files)
+
+	var rels []string
+	for rel := range files {
+		rels = append(rels, rel)
+	}
+	// Unreadable files fall back to the Maven layout
+	rels = append(rels, "app/src/main/java/com/x/gen/Generated.java")
+	res := newJavaImportResolver(newFileIndex(root, rels))
+
+	tests := []struct {
+		name string
+		imp  Import
+		want []string
+	}{
+		{"class", Import{Path: "com.x.model.User", Kind: "import"}, []string{"core/src/main/java/com/x/model/User.java"}},
+		{"declared package", Import{Path: "com.y.User", Kind: "import"}, []string{"legacy/User.java"}},
+		{"commented package", Import{Path: "com.y.orders.Order", Kind: "import"}, []string{"legacy/Order.java"}},
+		{"nested class", Import{Path: "com.x.model.User.Id", Kind: "import"}, []string{"core/src/main/java/com/x/model/User.java"}},
+		{"wildcard package", Import{Path: "com.x.model", Kind: "wildcard"}, []string{
+			"core/src/main/java/com/x/model/Group.java",
+			"core/src/main/java/com/x/model/User.java",
+		}},
+		{"wildcard class", Import{Path: "com.x.model.User", Kind: "wildcard"}, []string{"core/src/main/java/com/x/model/User.java"}},
+		{"static", Import{Path: "com.x.util.Strings.trim", Kind: "static"}, []string{"core/src/main/java/com/x/util/Strings.java"}},
+		{"static wildcard", Import{Path: "com.x.util.Strings", Kind: "static_wildcard"}, []string{"core/src/main/java/com/x/util/Strings.java"}},
+		{"default package", Import{Path: "AppTest", Kind: "import"}, []string{"app/src/test/java/com/x/AppTest.java"}},
+		{"layout", Import{Path: "com.x.gen.Generated", Kind: "import"}, []string{"app/src/main/java/com/x/gen/Generated.java"}},
+		{"external", Import{Path: "java.util.List", Kind: "import"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, res.Resolve(tt.imp))
+		})
+	}
+}
+
+// TestJavaImportDisambiguation verifies classes sharing a simple name are kept
+// apart by fully qualified name, and references prefer the imported class.
+func TestJavaImportDisambiguation(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"src/main/java/com/x/app/App.java":    "package com.x.app;\n\nimport static com.x.model.User.newUser;\n\nclass App {\n  void run() { newUser(); }\n}\n",
+		"src/main/java/com/x/model/User.java": "package com.x.model;\n\nclass User {\n  static User newUser() { return null; }\n}\n",
+		"src/main/java/com/y/User.java":       "package com.y;\n\nclass User {\n  static User newUser() { return null; }\n}\n",
+	})
+
+	r := &RepoMap{root: root, importWeight: 1}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	r.fileEdges = r.getFileEdges(fnames, allTags)
+
+	defines, _, _, _ := r.buildReferenceMaps(allTags)
+	assert.Contains(t, defines, "com.x.model.User")
+	assert.Contains(t, defines, "com.y.User")
+
+	var files []string
+	for _, tg := range r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{}) {
+		if tg.Name == "newUser" {
+			files = append(files, tg.FileName)
+		}
+	}
+	assert.Equal(t, []string{"src/main/java/com/x/model/User.java"}, files)
+}
@@ -0,0 +1,454 @@
+package germ
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// jsExtensions are the file extensions tried when resolving JS/TS modules.
+var jsExtensions = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// jsEmittedBy maps the extensions of emitted JavaScript files to the
+// extensions of the TypeScript sources they are compiled from.
+var jsEmittedBy = map[string][]string{
+	".js":  {".ts", ".tsx", ".d.ts"},
+	".jsx": {".tsx"},
+	".mjs": {".mts", ".d.mts"},
+	".cjs": {".cts", ".d.cts"},
+}
+
+// jsExportConditions are the package.json export conditions tried in order.
+// Source and type conditions come first since they usually point into the
+// repository rather than at build output.
+var jsExportConditions = []string{"source", "types", "import", "module", "require", "node", "default"}
+
+// jsImportResolver resolves JavaScript and TypeScript module specifiers to
+// files following Node resolution rules, tsconfig.json/jsconfig.json baseUrl
+// and paths, and package.json workspaces and exports.
+type jsImportResolver struct {
+	idx *fileIndex
+	// packages maps workspace package names to their package.json
+	packages map[string]*jsPackage
+	// tsconfigs caches the nearest tsconfig of each directory
+	tsconfigs map[string]*tsConfig
+}
+
+// jsPackage is the subset of a package.json used for module resolution.
+type jsPackage struct {
+	dir        string
+	Name       string          `json:"name"`
+	Main       string          `json:"main"`
+	Module     string          `json:"module"`
+	Types      string          `json:"types"`
+	Source     string          `json:"source"`
+	Exports    json.RawMessage `json:"exports"`
+	Workspaces json.RawMessage `json:"workspaces"`
+}
+
+// tsConfig is the subset of a tsconfig.json used for module resolution.
+// Directories are relative to the repository root.
+type tsConfig struct {
+	baseURL string
+	// pathsDir is the directory paths are relative to: baseUrl when set,
+	// otherwise the directory of the tsconfig declaring them
+	pathsDir string
+	paths    map[string][]string
+}
+
+func newJSImportResolver(idx *fileIndex) *jsImportResolver {
+	res := &jsImportResolver{
+		idx:       idx,
+		packages:  make(map[string]*jsPackage),
+		tsconfigs: make(map[string]*tsConfig),
+	}
+	res.loadWorkspaces()
+	return res
+}
+
+// Resolve returns the file a module specifier refers to.
+func (res *jsImportResolver) Resolve(imp Import) []string {
+	spec := imp.Path
+	dir := filepath.Dir(imp.FileName)
+
+	// 1) Relative specifiers
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".." {
+		return res.idx.first(jsCandidates(filepath.Join(dir, filepath.FromSlash(spec)))...)
+	}
+
+	// 2) tsconfig paths, then baseUrl
+	if tc := res.tsconfig(dir); tc != nil {
+		if files := res.resolvePaths(tc, spec); len(files) > 0 {
+			return files
+		}
+		if tc.baseURL != "" {
+			if files := res.idx.first(jsCandidates(filepath.Join(tc.baseURL, filepath.FromSlash(spec)))...); len(files) > 0 {
+				return files
+			}
+		}
+	}
+
+	// 3) Workspace packages
+	name, subpath := splitPackageSpecifier(spec)
+	if pkg, ok := res.packages[name]; ok {
+		if files := res.resolvePackage(pkg, subpath); len(files) > 0 {
+			return files
+		}
+	}
+
+	// 4) node_modules directories, walking up from the importing file
+	for d := dir; ; d = filepath.Dir(d) {
+		if files := res.idx.first(jsCandidates(filepath.Join(d, "node_modules", filepath.FromSlash(spec)))...); len(files) > 0 {
+			return files
+		}
+		if d == "." || d == string(filepath.Separator) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// resolvePaths applies the tsconfig paths mapping, preferring the pattern with
+// the longest prefix as TypeScript does.
+func (res *jsImportResolver) resolvePaths(tc *tsConfig, spec string) []string {
+	bestPattern, bestMatch := "", ""
+	bestLen := -1
+	for pattern := range tc.paths {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		switch {
+		case !wildcard && pattern == spec:
+			bestPattern, bestMatch, bestLen = pattern, "", len(pattern)+1
+		case wildcard && strings.HasPrefix(spec, prefix) && strings.HasSuffix(spec, suffix) &&
+			len(spec) >= len(prefix)+len(suffix) && len(prefix) > bestLen:
+			bestPattern, bestMatch, bestLen = pattern, spec[len(prefix):len(spec)-len(suffix)], len(prefix)
+		}
+	}
+	if bestLen < 0 {
+		return nil
+	}
+
+	for _, target := range tc.paths[bestPattern] {
+		p := filepath.Join(tc.pathsDir, filepath.FromSlash(strings.Replace(target, "*", bestMatch, 1)))
+		if files := res.idx.first(jsCandidates(p)...); len(files) > 0 {
+			return files
+		}
+	}
+	return nil
+}
+
+// resolvePackage resolves a subpath ("." or "./x") of a workspace package
+// through its exports, then its entry point fields, then the file layout.
+func (res *jsImportResolver) resolvePackage(pkg *jsPackage, subpath string) []string {
+	for _, target := range resolveExports(pkg.Exports, subpath) {
+		p := filepath.Join(pkg.dir, filepath.FromSlash(target))
+		if files := res.idx.first(jsCandidates(p)...); len(files) > 0 {
+			return files
+		}
+		if files := res.idx.first(jsCandidates(strings.TrimSuffix(p, filepath.Ext(p)))...); len(files) > 0 {
+			return files
+		}
+	}
+
+	if subpath == "." {
+		for _, entry := range []string{pkg.Source, pkg.Types, pkg.Module, pkg.Main} {
+			if entry == "" {
+				continue
+			}
+			if files := res.idx.first(jsCandidates(filepath.Join(pkg.dir, filepath.FromSlash(entry)))...); len(files) > 0 {
+				return files
+			}
+		}
+	}
+
+	return res.idx.first(jsCandidates(filepath.Join(pkg.dir, filepath.FromSlash(subpath)))...)
+}
+
+// resolveExports returns the targets a package.json exports field maps a
+// subpath to. It supports the string, conditions and subpath forms, including
+// subpath patterns such as "./features/*".
+func resolveExports(exports json.RawMessage, subpath string) []string {
+	if len(exports) == 0 {
+		return nil
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(exports, &m); err != nil {
+		// string or array form: only the package root is exported
+		if subpath != "." {
+			return nil
+		}
+		return exportTargets(exports, "")
+	}
+
+	// conditions form: only the package root is exported
+	isSubpathMap := false
+	for k := range m {
+		if strings.HasPrefix(k, ".") {
+			isSubpathMap = true
+			break
+		}
+	}
+	if !isSubpathMap {
+		if subpath != "." {
+			return nil
+		}
+		return exportTargets(exports, "")
+	}
+
+	if target, ok := m[subpath]; ok {
+		return exportTargets(target, "")
+	}
+
+	// Subpath patterns, longest prefix first
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, k := range keys {
+		prefix, suffix, wildcard := strings.Cut(k, "*")
+		if wildcard && strings.HasPrefix(subpath, prefix) && strings.HasSuffix(subpath, suffix) &&
+			len(subpath) >= len(prefix)+len(suffix) {
+			return exportTargets(m[k], subpath[len(prefix):len(subpath)-len(suffix)])
+		}
+	}
+	return nil
+}
+
+// exportTargets flattens an exports value (string, array or conditions) into
+// target paths, substituting match for any "*".
+func exportTargets(value json.RawMessage, match string) []string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return []string{strings.ReplaceAll(s, "*", match)}
+	}
+
+	var arr []json.RawMessage
+	if err := json.Unmarshal(value, &arr); err == nil {
+		var targets []string
+		for _, v := range arr {
+			targets = append(targets, exportTargets(v, match)...)
+		}
+		return targets
+	}
+
+	var conditions map[string]json.RawMessage
+	if err := json.Unmarshal(value, &conditions); err == nil {
+		var targets []string
+		for _, c := range jsExportConditions {
+			if v, ok := conditions[c]; ok {
+				targets = append(targets, exportTargets(v, match)...)
+			}
+		}
+		return targets
+	}
+	return nil
+}
+
+// splitPackageSpecifier splits a bare specifier into its package name and a
+// "./" prefixed subpath, eg. @scope/pkg/util -> (@scope/pkg, ./util).
+func splitPackageSpecifier(spec string) (string, string) {
+	parts := strings.Split(spec, "/")
+	n := 1
+	if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+		n = 2
+	}
+	if len(parts) <= n {
+		return spec, "."
+	}
+	return strings.Join(parts[:n], "/"), "./" + strings.Join(parts[n:], "/")
+}
+
+// loadWorkspaces reads the root package.json workspaces and indexes the
+// package.json of each matching directory by package name.
+func (res *jsImportResolver) loadWorkspaces() {
+	root := readPackageJSON(res.idx.root, ".")
+	if root == nil || len(root.Workspaces) == 0 {
+		return
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(root.Workspaces, &patterns); err != nil {
+		var ws struct {
+			Packages []string `json:"packages"`
+		}
+		if err := json.Unmarshal(root.Workspaces, &ws); err != nil {
+			return
+		}
+		patterns = ws.Packages
+	}
+
+	// Candidate package directories are the indexed directories and their parents
+	candidates := make(map[string]struct{})
+	for dir := range res.idx.dirs {
+		for d := dir; d != "." && d != string(filepath.Separator); d = filepath.Dir(d) {
+			candidates[d] = struct{}{}
+		}
+	}
+
+	for dir := range candidates {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(filepath.FromSlash(strings.TrimSuffix(pattern, "/")), dir); !ok {
+				continue
+			}
+			if pkg := readPackageJSON(res.idx.root, dir); pkg != nil && pkg.Name != "" {
+				res.packages[pkg.Name] = pkg
+			}
+		}
+	}
+}
+
+// readPackageJSON reads dir/package.json, returning nil if it is missing or invalid.
+func readPackageJSON(root, dir string) *jsPackage {
+	data, err := os.ReadFile(filepath.Join(root, dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	pkg := &jsPackage{dir: dir}
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil
+	}
+	return pkg
+}
+
+// tsconfig returns the nearest tsconfig.json or jsconfig.json at or above dir.
+func (res *jsImportResolver) tsconfig(dir string) *tsConfig {
+	if tc, ok := res.tsconfigs[dir]; ok {
+		return tc
+	}
+
+	var tc *tsConfig
+	for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+		if tc = readTSConfig(res.idx.root, filepath.Join(dir, name), 0); tc != nil {
+			break
+		}
+	}
+	if tc == nil && dir != "." && dir != string(filepath.Separator) {
+		tc = res.tsconfig(filepath.Dir(dir))
+	}
+
+	res.tsconfigs[dir] = tc
+	return tc
+}
+
+// readTSConfig reads a tsconfig file, following relative "extends" chains.
+func readTSConfig(root, rel string, depth int) *tsConfig {
+	data, err := os.ReadFile(filepath.Join(root, rel))
+	if err != nil || depth > 8 {
+		return nil
+	}
+
+	var raw struct {
+		Extends         string `json:"extends"`
+		CompilerOptions struct {
+			BaseURL string              `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	if err := json.Unmarshal(stripJSONComments(data), &raw); err != nil {
+		return nil
+	}
+
+	dir := filepath.Dir(rel)
+	tc := &tsConfig{pathsDir: dir}
+
+	if strings.HasPrefix(raw.Extends, ".") {
+		parent := filepath.Join(dir, filepath.FromSlash(raw.Extends))
+		if filepath.Ext(parent) != ".json" {
+			parent += ".json"
+		}
+		if base := readTSConfig(root, parent, depth+1); base != nil {
+			*tc = *base
+		}
+	}
+
+	if raw.CompilerOptions.BaseURL != "" {
+		tc.baseURL = filepath.Join(dir, filepath.FromSlash(raw.CompilerOptions.BaseURL))
+		tc.pathsDir = tc.baseURL
+	}
+	if raw.CompilerOptions.Paths != nil {
+		tc.paths = raw.CompilerOptions.Paths
+		if raw.CompilerOptions.BaseURL == "" && tc.baseURL == "" {
+			tc.pathsDir = dir
+		}
+	}
+
+	return tc
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas so that
+// JSONC files such as tsconfig.json can be decoded with encoding/json.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == '}' || c == ']':
+			// drop a trailing comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// jsCandidates lists the files a module path may refer to: the file itself,
+// its TypeScript source when it names the emitted JavaScript, as ESM
+// TypeScript imports do, the file with a known extension, or a directory index
+// file.
+func jsCandidates(p string) []string {
+	candidates := []string{p}
+	ext := filepath.Ext(p)
+	for _, tsExt := range jsEmittedBy[ext] {
+		candidates = append(candidates, strings.TrimSuffix(p, ext)+tsExt)
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, p+ext)
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, filepath.Join(p, "index"+ext))
+	}
+	return candidates
+}
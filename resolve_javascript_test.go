@@ -0,0 +1,90 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJSImportResolver verifies module specifiers resolve through relative
+// paths, tsconfig paths and baseUrl, workspace packages and node_modules.
+func TestJSImportResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"package.json":       `{"name": "monorepo", "workspaces": ["packages/*"]}`,
+		"tsconfig.base.json": "{\n  // shared options\n  \"compilerOptions\": {\n    \"baseUrl\": \"src\",\n    \"paths\": {\n      \"@app/*\": [\"app/*\"],\n    },\n  },\n}\n",
+		"tsconfig.json":      `{"extends": "./tsconfig.base.json"}`,
+		"packages/ui/package.json": `{
+  "name": "@acme/ui",
+  "exports": {
+    ".": {"types": "./src/index.ts", "default": "./dist/index.js"},
+    "./icons/*": "./src/icons/*.tsx"
+  }
+}`,
+		"packages/legacy/package.json": `{"name": "legacy", "main": "lib/main.js"}`,
+	})
+
+	rels := []string{
+		"src/main.ts",
+		"src/util/format.ts",
+		"src/util/parse.ts",
+		"src/util/view.tsx",
+		"src/util/worker.mts",
+		"src/app/store/index.ts",
+		"src/config.ts",
+		"packages/ui/src/index.ts",
+		"packages/ui/src/icons/close.tsx",
+		"packages/legacy/lib/main.js",
+		"node_modules/left-pad/index.js",
+	}
+	res := newJSImportResolver(newFileIndex(root, rels))
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"./util/format", []string{"src/util/format.ts"}},
+		{"./util/parse.js", []string{"src/util/parse.ts"}},
+		{"./util/view.js", []string{"src/util/view.tsx"}},
+		{"./util/worker.mjs", []string{"src/util/worker.mts"}},
+		{"@app/store", []string{"src/app/store/index.ts"}},
+		{"config", []string{"src/config.ts"}},
+		{"@acme/ui", []string{"packages/ui/src/index.ts"}},
+		{"@acme/ui/icons/close", []string{"packages/ui/src/icons/close.tsx"}},
+		{"legacy", []string{"packages/legacy/lib/main.js"}},
+		{"left-pad", []string{"node_modules/left-pad/index.js"}},
+		{"react", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got := res.Resolve(Import{FileName: "src/main.ts", Path: tt.spec, Lang: "typescript"})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestImportDisambiguation verifies a reference only ranks the definition its
+// file imports when a symbol is defined in several files.
+func TestImportDisambiguation(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"src/app.js":           "import { Button } from './ui/button';\nexport function App() { return Button(); }\n",
+		"src/ui/button.js":     "export function Button() { return 1; }\n",
+		"src/legacy/button.js": "export function Button() { return 2; }\n",
+	})
+
+	r := &RepoMap{root: root, importWeight: 1}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	r.fileEdges = r.getFileEdges(fnames, allTags)
+
+	ranked := r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+
+	var files []string
+	for _, tg := range ranked {
+		if tg.Name == "Button" {
+			files = append(files, tg.FileName)
+		}
+	}
+	assert.Equal(t, []string{"src/ui/button.js"}, files)
+}
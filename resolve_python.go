@@ -0,0 +1,135 @@
+package germ
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pythonProjectFiles mark the directory of a Python project, whose src/
+// directory is a source root.
+var pythonProjectFiles = []string{"pyproject.toml", "setup.py", "setup.cfg"}
+
+// pythonImportResolver resolves dotted and relative Python module names to
+// module files or package __init__.py files, searching each source root.
+// Packages without __init__.py are namespace packages.
+type pythonImportResolver struct {
+	idx *fileIndex
+	// roots are the relative source roots searched for absolute imports
+	roots []string
+}
+
+// newPythonImportResolver searches the configured source roots first, then
+// the repository root and the src/ directory of each Python project.
+func newPythonImportResolver(idx *fileIndex, extraRoots []string) *pythonImportResolver {
+	res := &pythonImportResolver{idx: idx}
+
+	seen := make(map[string]struct{})
+	add := func(root string) {
+		root = filepath.Clean(root)
+		if _, ok := seen[root]; !ok {
+			seen[root] = struct{}{}
+			res.roots = append(res.roots, root)
+		}
+	}
+
+	for _, root := range extraRoots {
+		if filepath.IsAbs(root) {
+			if rel, err := filepath.Rel(idx.root, root); err == nil {
+				root = rel
+			}
+		}
+		add(root)
+	}
+	add(".")
+
+	var srcRoots []string
+	for dir := range idx.dirs {
+		for d := dir; ; d = filepath.Dir(d) {
+			if filepath.Base(d) == "src" && isPythonProject(filepath.Join(idx.root, filepath.Dir(d))) {
+				srcRoots = append(srcRoots, d)
+				break
+			}
+			if d == "." || d == string(filepath.Separator) {
+				break
+			}
+		}
+	}
+	sort.Strings(srcRoots)
+	for _, root := range srcRoots {
+		add(root)
+	}
+
+	return res
+}
+
+// isPythonProject reports whether dir holds a Python project file.
+func isPythonProject(dir string) bool {
+	for _, name := range pythonProjectFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the module file of a Python import. A member import, eg.
+// `from app import db`, resolves to the submodule when there is one, or to
+// the module defining the member otherwise.
+func (res *pythonImportResolver) Resolve(imp Import) []string {
+	name := imp.Path
+
+	if dsts := res.resolveModule(imp.FileName, name); len(dsts) > 0 || imp.Kind != "member" {
+		return dsts
+	}
+
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return nil
+	}
+	module := name[:i]
+	if module == "" || strings.HasSuffix(module, ".") {
+		// `from . import x` where x is not a module: the package itself
+		module = name[:i+1]
+	}
+	return res.resolveModule(imp.FileName, module)
+}
+
+// resolveModule returns the file of a dotted module name, relative to the
+// importing package when the name starts with dots.
+func (res *pythonImportResolver) resolveModule(fname, name string) []string {
+	bases := res.roots
+
+	// Relative imports start from the importing package, one level up per extra dot
+	if strings.HasPrefix(name, ".") {
+		dots := len(name) - len(strings.TrimLeft(name, "."))
+		name = name[dots:]
+		base := filepath.Dir(fname)
+		for i := 1; i < dots; i++ {
+			base = filepath.Dir(base)
+		}
+		bases = []string{base}
+	}
+
+	for _, base := range bases {
+		p := filepath.Join(base, filepath.FromSlash(strings.ReplaceAll(name, ".", "/")))
+		candidates := []string{p + ".py", filepath.Join(p, "__init__.py")}
+		if name == "" {
+			candidates = candidates[1:]
+		}
+		if dsts := res.idx.first(candidates...); len(dsts) > 0 {
+			return dsts
+		}
+	}
+	return nil
+}
+
+// joinModulePath qualifies an imported member with its module, eg. app and db
+// give app.db while . and util give .util.
+func joinModulePath(module, member string) string {
+	if strings.HasSuffix(module, ".") {
+		return module + member
+	}
+	return module + "." + member
+}
@@ -0,0 +1,83 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPythonImportResolver verifies Python imports resolve through packages,
+// src/ layouts, namespace packages, relative imports and extra source roots.
+func TestPythonImportResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"pyproject.toml": "[project]\nname = \"app\"\n",
+	})
+
+	rels := []string{
+		"src/app/__init__.py",
+		"src/app/db.py",
+		"src/app/api/__init__.py",
+		"src/app/api/routes.py",
+		"src/app/api/views.py",
+		"plugins/ns/tools.py",
+		"lib/shared/log.py",
+	}
+	res := newPythonImportResolver(newFileIndex(root, rels), []string{"lib"})
+
+	tests := []struct {
+		name string
+		imp  Import
+		want []string
+	}{
+		{"src layout", Import{Path: "app.db", Kind: "import"}, []string{"src/app/db.py"}},
+		{"package", Import{Path: "app", Kind: "import"}, []string{"src/app/__init__.py"}},
+		{"member symbol", Import{Path: "app.db.Session", Kind: "member"}, []string{"src/app/db.py"}},
+		{"member submodule", Import{Path: "app.api.routes", Kind: "member"}, []string{"src/app/api/routes.py"}},
+		{"relative", Import{Path: ".routes", Kind: "from"}, []string{"src/app/api/routes.py"}},
+		{"relative member", Import{Path: ".views", Kind: "member"}, []string{"src/app/api/views.py"}},
+		{"relative package member", Import{Path: ".helper", Kind: "member"}, []string{"src/app/api/__init__.py"}},
+		{"parent", Import{Path: "..db", Kind: "from"}, []string{"src/app/db.py"}},
+		{"namespace package", Import{Path: "plugins.ns.tools", Kind: "import"}, []string{"plugins/ns/tools.py"}},
+		{"source root", Import{Path: "shared.log", Kind: "import"}, []string{"lib/shared/log.py"}},
+		{"external", Import{Path: "os.path", Kind: "import"}, nil},
+		{"external member", Import{Path: "os.path", Kind: "member"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.imp.FileName = "src/app/api/routes.py"
+			assert.Equal(t, tt.want, res.Resolve(tt.imp))
+		})
+	}
+}
+
+// TestPythonImportDisambiguation verifies a `from ... import` makes references
+// prefer the imported definition.
+func TestPythonImportDisambiguation(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"pyproject.toml":         "[project]\nname = \"app\"\n",
+		"src/app/__init__.py":    "",
+		"src/app/db.py":          "class Session:\n    pass\n",
+		"src/legacy/db.py":       "class Session:\n    pass\n",
+		"src/app/handlers.py":    "from app.db import Session\n\ndef handle():\n    return Session()\n",
+		"src/legacy/__init__.py": "",
+	})
+
+	r := &RepoMap{root: root, importWeight: 1}
+	deps := r.GetDependencyGraph(fnames)
+	assert.Equal(t, []string{"src/app/db.py"}, deps.Imports("src/app/handlers.py"))
+	assert.Empty(t, deps.Packages["src/app/handlers.py"])
+
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+	r.fileEdges = r.getFileEdges(fnames, allTags)
+
+	var files []string
+	for _, tg := range r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{}) {
+		if tg.Name == "Session" {
+			files = append(files, tg.FileName)
+		}
+	}
+	assert.Equal(t, []string{"src/app/db.py"}, files)
+}
@@ -0,0 +1,348 @@
+package germ
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rustCrateRoots are the module tree roots of a crate, relative to its src/
+// directory, in lookup order.
+var rustCrateRoots = []string{"lib.rs", "main.rs"}
+
+// rustCrate is a Cargo package of the repository.
+type rustCrate struct {
+	// name is the package name as used in paths, ie. with - replaced by _
+	name string
+	// dir is the directory of Cargo.toml, relative to the root
+	dir string
+	// lib is the library crate root when not src/lib.rs
+	lib string
+}
+
+// rustImportResolver resolves `mod foo;` declarations to foo.rs or foo/mod.rs,
+// and `use` paths to the file of the deepest module they name, following the
+// module tree of each Cargo package in the repository.
+type rustImportResolver struct {
+	idx *fileIndex
+	// crates maps package directories to their crate
+	crates map[string]*rustCrate
+	// byName maps crate names to their crate
+	byName map[string]*rustCrate
+}
+
+// newRustImportResolver reads the Cargo.toml of every indexed directory and its
+// parents, so workspace members can use each other by crate name.
+func newRustImportResolver(idx *fileIndex) *rustImportResolver {
+	res := &rustImportResolver{
+		idx:    idx,
+		crates: make(map[string]*rustCrate),
+		byName: make(map[string]*rustCrate),
+	}
+
+	seen := make(map[string]struct{})
+	for dir := range idx.dirs {
+		for d := dir; ; d = filepath.Dir(d) {
+			if _, ok := seen[d]; ok {
+				break
+			}
+			seen[d] = struct{}{}
+			if crate := readCargoManifest(idx.root, d); crate != nil {
+				res.crates[d] = crate
+				res.byName[crate.name] = crate
+			}
+			if d == "." || d == string(filepath.Separator) {
+				break
+			}
+		}
+	}
+	return res
+}
+
+// readCargoManifest returns the package declared by dir/Cargo.toml, or nil for
+// a missing manifest or a virtual workspace manifest.
+func readCargoManifest(root, dir string) *rustCrate {
+	f, err := os.Open(filepath.Join(root, dir, "Cargo.toml"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	crate := &rustCrate{dir: dir}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch {
+		case section == "package" && key == "name" && crate.name == "":
+			crate.name = strings.ReplaceAll(value, "-", "_")
+		case section == "lib" && key == "name":
+			crate.name = strings.ReplaceAll(value, "-", "_")
+		case section == "lib" && key == "path":
+			crate.lib = filepath.Join(dir, filepath.FromSlash(value))
+		}
+	}
+
+	if crate.name == "" {
+		return nil
+	}
+	return crate
+}
+
+// crateOf returns the crate owning a file, ie. the one with the deepest
+// package directory containing it, and the file's module path in the crate.
+// Files outside a package's src/ directory get a nil module path.
+func (res *rustImportResolver) crateOf(fname string) (*rustCrate, []string) {
+	for d := filepath.Dir(fname); ; d = filepath.Dir(d) {
+		if crate, ok := res.crates[d]; ok {
+			return crate, rustModulePath(crate, fname)
+		}
+		if d == "." || d == string(filepath.Separator) {
+			return nil, nil
+		}
+	}
+}
+
+// rustModulePath returns the module path of a file in a crate, eg.
+// [net http] for src/net/http.rs or src/net/http/mod.rs, and an empty path
+// for crate roots.
+func rustModulePath(crate *rustCrate, fname string) []string {
+	if fname == crate.lib {
+		return []string{}
+	}
+	rel, err := filepath.Rel(filepath.Join(crate.dir, "src"), fname)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+
+	segs := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, ".rs")), "/")
+	switch {
+	case len(segs) == 1 && (segs[0] == "lib" || segs[0] == "main"):
+		return []string{}
+	case len(segs) == 2 && segs[0] == "bin":
+		// Binaries are crate roots of their own
+		return []string{}
+	case segs[len(segs)-1] == "mod":
+		segs = segs[:len(segs)-1]
+	}
+	return segs
+}
+
+// moduleFile returns the file defining a module of a crate, if indexed.
+func (res *rustImportResolver) moduleFile(crate *rustCrate, path []string) string {
+	src := filepath.Join(crate.dir, "src")
+
+	var candidates []string
+	if len(path) == 0 {
+		if crate.lib != "" {
+			candidates = append(candidates, crate.lib)
+		}
+		for _, root := range rustCrateRoots {
+			candidates = append(candidates, filepath.Join(src, root))
+		}
+	} else {
+		p := filepath.Join(src, filepath.Join(path...))
+		candidates = append(candidates, p+".rs", filepath.Join(p, "mod.rs"))
+	}
+
+	if dsts := res.idx.first(candidates...); len(dsts) > 0 {
+		return dsts[0]
+	}
+	return ""
+}
+
+// Resolve returns the file of a Rust module declaration, or the files of the
+// modules named by the paths of a use declaration.
+func (res *rustImportResolver) Resolve(imp Import) []string {
+	switch imp.Kind {
+	case "mod":
+		dir := rustModuleDir(imp.FileName)
+		return res.idx.first(filepath.Join(dir, imp.Path+".rs"), filepath.Join(dir, imp.Path, "mod.rs"))
+	case "use":
+		var files []string
+		for _, path := range expandUseTree(nil, imp.Path) {
+			if f := res.resolveUsePath(imp.FileName, path); f != "" {
+				files = appendUnique(files, f)
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+	return nil
+}
+
+// resolveUsePath returns the file of the deepest module named by a use path.
+// Paths start at the crate root (crate::), the current module (self::), a
+// parent module (super::), another crate of the repository, or a child
+// module of the current module. Other paths are external.
+func (res *rustImportResolver) resolveUsePath(fname string, path []string) string {
+	crate, current := res.crateOf(fname)
+	if len(path) == 0 {
+		return ""
+	}
+
+	var mod []string
+	rest := path
+	switch first := path[0]; {
+	case first == "crate" || first == "self" || first == "super":
+		if crate == nil || current == nil {
+			return ""
+		}
+		if first == "crate" {
+			mod, rest = []string{}, path[1:]
+			break
+		}
+		mod = append([]string{}, current...)
+		for rest = path; len(rest) > 0 && (rest[0] == "self" || rest[0] == "super"); rest = rest[1:] {
+			if rest[0] == "super" {
+				if len(mod) == 0 {
+					return ""
+				}
+				mod = mod[:len(mod)-1]
+			}
+		}
+	case res.byName[first] != nil:
+		crate, mod, rest = res.byName[first], []string{}, path[1:]
+	default:
+		// A child module of the current module, eg. after `mod net;`
+		if crate == nil || current == nil {
+			return ""
+		}
+		mod = append(append([]string{}, current...), first)
+		if res.moduleFile(crate, mod) == "" {
+			return ""
+		}
+		rest = path[1:]
+	}
+
+	file := res.moduleFile(crate, mod)
+	for _, seg := range rest {
+		next := res.moduleFile(crate, append(mod, seg))
+		if next == "" {
+			break
+		}
+		mod, file = append(mod, seg), next
+	}
+	return file
+}
+
+// expandUseTree flattens a use tree into its paths, eg. a::{b, c::{d, e as f}}
+// gives [a b], [a c d] and [a c e]. Globs and self name their parent module.
+func expandUseTree(prefix []string, tree string) [][]string {
+	tree = strings.TrimPrefix(strings.TrimSpace(tree), "::")
+
+	if i := strings.Index(tree, "{"); i >= 0 {
+		head := splitUsePath(strings.TrimSuffix(strings.TrimSpace(tree[:i]), "::"))
+		body := tree[i+1:]
+		if j := strings.LastIndex(body, "}"); j >= 0 {
+			body = body[:j]
+		}
+
+		base := append(append([]string{}, prefix...), head...)
+		var paths [][]string
+		for _, item := range splitTopLevel(body) {
+			if strings.TrimSpace(item) != "" {
+				paths = append(paths, expandUseTree(base, item)...)
+			}
+		}
+		return paths
+	}
+
+	if before, _, ok := strings.Cut(tree, " as "); ok {
+		tree = before
+	}
+	path := append(append([]string{}, prefix...), splitUsePath(tree)...)
+	if n := len(path); n > 0 && (path[n-1] == "*" || path[n-1] == "self") {
+		path = path[:n-1]
+	}
+	return [][]string{path}
+}
+
+// splitUsePath splits a :: separated path into its segments.
+func splitUsePath(path string) []string {
+	var segs []string
+	for _, s := range strings.Split(path, "::") {
+		if s = strings.TrimSpace(s); s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// splitTopLevel splits s on the commas that are not nested in braces.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// rustModuleDir returns the directory holding the child modules of a file:
+// its own directory for mod.rs, lib.rs and main.rs, or a directory named
+// after the file otherwise.
+func rustModuleDir(fname string) string {
+	switch filepath.Base(fname) {
+	case "mod.rs", "lib.rs", "main.rs":
+		return filepath.Dir(fname)
+	}
+	return strings.TrimSuffix(fname, ".rs")
+}
+
+// rustScope returns the crate and module path qualifying the definitions of a
+// file, eg. [app net] for src/net.rs of the app crate, or nil when the file
+// belongs to no Cargo package.
+func (r *RepoMap) rustScope(fname string) []string {
+	crate := r.rustCrateOf(filepath.Dir(fname))
+	if crate == nil {
+		return nil
+	}
+	path := rustModulePath(crate, fname)
+	if path == nil {
+		return nil
+	}
+	return append([]string{crate.name}, path...)
+}
+
+// rustCrateOf returns the Cargo package of the nearest manifest at or above
+// dir, or nil. Manifests are read once per directory, see rustCrates.
+func (r *RepoMap) rustCrateOf(dir string) *rustCrate {
+	if crate, ok := r.rustCrates[dir]; ok {
+		return crate
+	}
+
+	crate := readCargoManifest(r.root, dir)
+	if crate == nil && dir != "." && dir != string(filepath.Separator) {
+		crate = r.rustCrateOf(filepath.Dir(dir))
+	}
+
+	if r.rustCrates == nil {
+		r.rustCrates = make(map[string]*rustCrate)
+	}
+	r.rustCrates[dir] = crate
+	return crate
+}
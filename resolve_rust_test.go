@@ -0,0 +1,137 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExpandUseTree verifies nested use trees flatten into paths.
+func TestExpandUseTree(t *testing.T) {
+	got := expandUseTree(nil, "crate::net::{self, http::{Client, Server as S}, tcp::*}")
+	assert.Equal(t, [][]string{
+		{"crate", "net"},
+		{"crate", "net", "http", "Client"},
+		{"crate", "net", "http", "Server"},
+		{"crate", "net", "tcp"},
+	}, got)
+
+	assert.Equal(t, [][]string{{"std", "io"}}, expandUseTree(nil, "::std::io"))
+}
+
+// TestRustImportResolver verifies mod declarations and use paths resolve
+// through the module tree of each workspace crate.
+func TestRustImportResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"Cargo.toml":            "[workspace]\nmembers = [\"app\", \"core-lib\"]\n",
+		"app/Cargo.toml":        "[package]\nname = \"app\"\n",
+		"core-lib/Cargo.toml":   "[package]\nname = \"core-lib\"\nversion = \"0.1.0\"\n\n[dependencies]\nserde = \"1\"\n",
+		"app/src/main.rs":       "",
+		"app/src/net/mod.rs":    "",
+		"app/src/net/http.rs":   "",
+		"app/src/net/tcp.rs":    "",
+		"app/src/util.rs":       "",
+		"core-lib/src/lib.rs":   "",
+		"core-lib/src/model.rs": "",
+	})
+
+	rels := []string{
+		"app/src/main.rs",
+		"app/src/net/mod.rs",
+		"app/src/net/http.rs",
+		"app/src/net/tcp.rs",
+		"app/src/util.rs",
+		"core-lib/src/lib.rs",
+		"core-lib/src/model.rs",
+	}
+	res := newRustImportResolver(newFileIndex(root, rels))
+
+	tests := []struct {
+		name string
+		imp  Import
+		want []string
+	}{
+		{"mod file", Import{FileName: "app/src/main.rs", Path: "util", Kind: "mod"}, []string{"app/src/util.rs"}},
+		{"mod dir", Import{FileName: "app/src/main.rs", Path: "net", Kind: "mod"}, []string{"app/src/net/mod.rs"}},
+		{"nested mod", Import{FileName: "app/src/net/mod.rs", Path: "http", Kind: "mod"}, []string{"app/src/net/http.rs"}},
+		{"crate item", Import{FileName: "app/src/util.rs", Path: "crate::net::http::Client", Kind: "use"}, []string{"app/src/net/http.rs"}},
+		{"crate root item", Import{FileName: "app/src/util.rs", Path: "crate::Config", Kind: "use"}, []string{"app/src/main.rs"}},
+		{"super", Import{FileName: "app/src/net/http.rs", Path: "super::tcp::connect", Kind: "use"}, []string{"app/src/net/tcp.rs"}},
+		{"super item", Import{FileName: "app/src/net/http.rs", Path: "super::Conn", Kind: "use"}, []string{"app/src/net/mod.rs"}},
+		{"self", Import{FileName: "app/src/net/mod.rs", Path: "self::http::Client", Kind: "use"}, []string{"app/src/net/http.rs"}},
+		{"child module", Import{FileName: "app/src/main.rs", Path: "util::helper", Kind: "use"}, []string{"app/src/util.rs"}},
+		{"tree", Import{FileName: "app/src/main.rs", Path: "crate::net::{http::Client, tcp}", Kind: "use"}, []string{"app/src/net/http.rs", "app/src/net/tcp.rs"}},
+		{"workspace crate", Import{FileName: "app/src/main.rs", Path: "core_lib::model::User", Kind: "use"}, []string{"core-lib/src/model.rs"}},
+		{"external", Import{FileName: "app/src/main.rs", Path: "std::collections::HashMap", Kind: "use"}, nil},
+		{"super at root", Import{FileName: "app/src/main.rs", Path: "super::x", Kind: "use"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, res.Resolve(tt.imp))
+		})
+	}
+}
+
+// TestRustQualifiedNames verifies Rust definitions are qualified by crate and
+// module path, and qualified calls resolve to the matching definition.
+func TestRustQualifiedNames(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"Cargo.toml":       "[package]\nname = \"my-app\"\n",
+		"src/main.rs":      "mod db;\nmod cache;\n\nfn main() {\n    db::connect();\n}\n",
+		"src/db.rs":        "pub struct Pool;\n\nimpl Pool {\n    pub fn acquire() {}\n}\n\npub fn connect() {}\n",
+		"src/cache/mod.rs": "pub fn connect() {}\n",
+	})
+
+	r := &RepoMap{root: root}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+
+	acquire := findTag(allTags, TagKindDef, "acquire")
+	if assert.NotNil(t, acquire) {
+		assert.Equal(t, "my_app.db.Pool.acquire", acquire.QualifiedName())
+	}
+	connect := findTag(allTags, TagKindRef, "connect")
+	if assert.NotNil(t, connect) {
+		assert.Equal(t, "db.connect", connect.QualifiedName())
+	}
+
+	defines, _, _, _ := r.buildReferenceMaps(allTags)
+	assert.Contains(t, defines, "my_app.cache.connect")
+	assert.Contains(t, defines, "my_app.db.connect")
+
+	var files []string
+	for _, tg := range r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{}) {
+		if tg.Name == "connect" {
+			files = append(files, tg.FileName)
+		}
+	}
+	assert.Equal(t, []string{"src/db.rs"}, files)
+}
+
+// TestRustScopeCache verifies Cargo manifests are read once per directory
+// for a set of tags, and read again for the next one.
+func TestRustScopeCache(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"Cargo.toml":     "[package]\nname = \"app\"\n",
+		"src/net/mod.rs": "pub fn connect() {}\n",
+		"src/net/tcp.rs": "pub fn listen() {}\n",
+	})
+
+	r := &RepoMap{root: root}
+	r.getTagsFromFiles(fnames, commonWords)
+	assert.Equal(t, "app", r.rustCrates["."].name)
+	assert.Same(t, r.rustCrates["."], r.rustCrates[filepath.Join("src", "net")])
+
+	// The cached crate is used until the next set of tags
+	writeTestFiles(t, root, map[string]string{"Cargo.toml": "[package]\nname = \"server\"\n"})
+	assert.Equal(t, []string{"app", "net", "tcp"}, r.rustScope(filepath.Join("src", "net", "tcp.rs")))
+
+	listen := findTag(r.getTagsFromFiles(fnames, commonWords), TagKindDef, "listen")
+	if assert.NotNil(t, listen) {
+		assert.Equal(t, "server.net.tcp.listen", listen.QualifiedName())
+	}
+}
@@ -0,0 +1,219 @@
+package germ
+
+import (
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// scopeNameFields maps the CST node kinds that open a named scope (package,
+// namespace, module, class, receiver, ...) to the field holding their name.
+var scopeNameFields = map[string]string{
+	// Go: a method's scope is its receiver type
+	"type_spec":          "name",
+	"method_declaration": "receiver",
+	// Python
+	"class_definition":    "name",
+	"function_definition": "name",
+	// JavaScript / TypeScript
+	"class":                      "name",
+	"class_declaration":          "name",
+	"abstract_class_declaration": "name",
+	"interface_declaration":      "name",
+	"internal_module":            "name",
+	"function_declaration":       "name",
+	"method_definition":          "name",
+	// Java / C#
+	"enum_declaration":      "name",
+	"record_declaration":    "name",
+	"struct_declaration":    "name",
+	"namespace_declaration": "name",
+	// Rust: an impl block's scope is the implementing type
+	"mod_item":   "name",
+	"impl_item":  "type",
+	"trait_item": "name",
+	// C++
+	"namespace_definition": "name",
+	"class_specifier":      "name",
+	"struct_specifier":     "name",
+	// Ruby
+	"module": "name",
+}
+
+// qualifierFields lists the fields holding the left-hand side of a qualified
+// reference, eg. `pkg` in pkg.Func, obj.method or Type::new.
+var qualifierFields = []string{"operand", "package", "object", "path", "value", "expression", "scope"}
+
+// QualifiedName returns the tag name prefixed by its enclosing scope chain,
+// eg. srv.Server.Close.
+func (t Tag) QualifiedName() string {
+	if len(t.Scope) == 0 {
+		return t.Name
+	}
+	return strings.Join(t.Scope, ".") + "." + t.Name
+}
+
+// tagScope returns the scope chain of a captured node. Definitions get the
+// names of all their enclosing scopes, outermost first, starting with the
+// package (Go, Java) when the file declares one, and the qualifier of C++
+// out-of-line definitions. References only get the qualifier they were
+// written with, if any.
+func tagScope(node *sitter.Node, sourceCode []byte, kind string) []string {
+	if kind == TagKindRef {
+		return referenceQualifier(node, sourceCode)
+	}
+
+	var scope []string
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		if p.Parent() == nil {
+			if pkg := packageName(p, sourceCode); pkg != "" {
+				scope = append(scope, pkg)
+			}
+			break
+		}
+
+		// C++ out-of-line definitions, eg. Foo::bar or ns::Foo::bar, are
+		// scoped by their qualifier
+		if p.Kind() == "qualified_identifier" {
+			if q := p.ChildByFieldName("scope"); q != nil && q.EndByte() <= node.StartByte() {
+				scope = append(scope, scopeName(q, sourceCode))
+			}
+			continue
+		}
+
+		field, ok := scopeNameFields[p.Kind()]
+		if !ok {
+			continue
+		}
+		nameNode := p.ChildByFieldName(field)
+		if nameNode == nil || nameNode.StartByte() == node.StartByte() {
+			continue
+		}
+		if name := scopeName(nameNode, sourceCode); name != "" {
+			scope = append(scope, name)
+		}
+	}
+
+	// Reverse so the outermost scope comes first
+	for i, j := 0, len(scope)-1; i < j; i, j = i+1, j-1 {
+		scope[i], scope[j] = scope[j], scope[i]
+	}
+	return scope
+}
+
+// scopeName returns the name held by a scope's name field. Receivers and impl
+// targets may be pointer or generic types, in which case the base type name is
+// used.
+func scopeName(n *sitter.Node, sourceCode []byte) string {
+	switch n.Kind() {
+	case "identifier", "type_identifier", "field_identifier", "property_identifier", "constant", "namespace_identifier":
+		return n.Utf8Text(sourceCode)
+	}
+	if id := firstDescendant(n, "type_identifier"); id != nil {
+		return id.Utf8Text(sourceCode)
+	}
+	return n.Utf8Text(sourceCode)
+}
+
+// packageName returns the package declared at the top of a file, if any.
+func packageName(root *sitter.Node, sourceCode []byte) string {
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		child := root.NamedChild(i)
+		switch child.Kind() {
+		case "package_clause", "package_declaration":
+			if child.NamedChildCount() > 0 {
+				return child.NamedChild(0).Utf8Text(sourceCode)
+			}
+		case "file_scoped_namespace_declaration":
+			if name := child.ChildByFieldName("name"); name != nil {
+				return name.Utf8Text(sourceCode)
+			}
+		}
+	}
+	return ""
+}
+
+// referenceQualifier returns the identifiers qualifying a reference, eg.
+// ["http"] for http.Get or ["a", "b"] for a::b::c, or nil.
+func referenceQualifier(node *sitter.Node, sourceCode []byte) []string {
+	parent := node.Parent()
+	if parent == nil {
+		return nil
+	}
+	for _, field := range qualifierFields {
+		q := parent.ChildByFieldName(field)
+		if q == nil || q.StartByte() == node.StartByte() {
+			continue
+		}
+		qualifier := qualifierParts(q, sourceCode)
+		if qualifier == nil {
+			return nil
+		}
+
+		// C++ nests a::b::c to the right, the outer qualifiers are held by
+		// the enclosing qualified identifiers
+		for p := parent; p.Parent() != nil && p.Parent().Kind() == "qualified_identifier"; p = p.Parent() {
+			outer := p.Parent().ChildByFieldName("scope")
+			if outer == nil || outer.StartByte() == p.StartByte() {
+				break
+			}
+			outerParts := qualifierParts(outer, sourceCode)
+			if outerParts == nil {
+				break
+			}
+			qualifier = append(outerParts, qualifier...)
+		}
+		return qualifier
+	}
+	return nil
+}
+
+// qualifierParts splits a qualifier into its identifiers, eg. ["a", "b"] for
+// the Rust path a::b. Paths relative to the crate or module (crate, self,
+// super) keep the identifiers following them. It returns nil for qualifiers
+// that are not identifiers, eg. call results.
+func qualifierParts(q *sitter.Node, sourceCode []byte) []string {
+	switch q.Kind() {
+	case "scoped_identifier", "scoped_type_identifier", "qualified_identifier":
+		name := q.ChildByFieldName("name")
+		if name == nil {
+			return nil
+		}
+		nameParts := qualifierParts(name, sourceCode)
+		if nameParts == nil {
+			return nil
+		}
+		left := q.ChildByFieldName("path")
+		if left == nil {
+			left = q.ChildByFieldName("scope")
+		}
+		if left == nil {
+			return nameParts
+		}
+		leftParts := qualifierParts(left, sourceCode)
+		if leftParts == nil {
+			return nil
+		}
+		return append(leftParts, nameParts...)
+	case "crate", "self", "super":
+		return []string{}
+	}
+	if strings.HasSuffix(q.Kind(), "identifier") {
+		return []string{q.Utf8Text(sourceCode)}
+	}
+	return nil
+}
+
+// firstDescendant returns the first node of the given kind in a depth-first
+// walk of n, or nil.
+func firstDescendant(n *sitter.Node, kind string) *sitter.Node {
+	if n.Kind() == kind {
+		return n
+	}
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		if d := firstDescendant(n.NamedChild(i), kind); d != nil {
+			return d
+		}
+	}
+	return nil
+}
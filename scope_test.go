@@ -0,0 +1,194 @@
+package germ
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTagScope verifies definitions carry their enclosing scope chain and
+// qualified references carry their qualifier.
+func TestTagScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		fname  string
+		source string
+		kind   string
+		tag    string
+		want   string
+	}{
+		{
+			name:  "Go method receiver",
+			fname: "server.go",
+			source: `package srv
+
+func (s *Server) Close() error { return nil }
+`,
+			kind: TagKindDef,
+			tag:  "Close",
+			want: "srv.Server.Close",
+		},
+		{
+			name:  "Go generic receiver",
+			fname: "client.go",
+			source: `package srv
+
+func (c Client[T]) Close() {}
+`,
+			kind: TagKindDef,
+			tag:  "Close",
+			want: "srv.Client.Close",
+		},
+		{
+			name:  "Go struct field",
+			fname: "config.go",
+			source: `package srv
+
+type Config struct {
+	Timeout int
+}
+`,
+			kind: TagKindDef,
+			tag:  "Timeout",
+			want: "srv.Config.Timeout",
+		},
+		{
+			name:  "Go package qualified call",
+			fname: "main.go",
+			source: `package main
+
+func main() { http.Get("/") }
+`,
+			kind: TagKindRef,
+			tag:  "Get",
+			want: "http.Get",
+		},
+		{
+			name:  "Python class method",
+			fname: "greeter.py",
+			source: `class Greeter:
+    def greet(self):
+        pass
+`,
+			kind: TagKindDef,
+			tag:  "greet",
+			want: "Greeter.greet",
+		},
+		{
+			name:  "Java package and nested class",
+			fname: "Outer.java",
+			source: `package com.example.app;
+
+public class Outer {
+    class Inner {
+        void run() {}
+    }
+}
+`,
+			kind: TagKindDef,
+			tag:  "run",
+			want: "com.example.app.Outer.Inner.run",
+		},
+		{
+			name:  "Rust impl block",
+			fname: "lib.rs",
+			source: `impl<T> Display for Server<T> {
+    fn fmt(&self) {}
+}
+`,
+			kind: TagKindDef,
+			tag:  "fmt",
+			want: "Server.fmt",
+		},
+		{
+			name:  "Rust multi-segment path call",
+			fname: "main.rs",
+			source: `fn main() {
+    net::http::fetch();
+}
+`,
+			kind: TagKindRef,
+			tag:  "fetch",
+			want: "net.http.fetch",
+		},
+		{
+			name:  "Rust crate relative path call",
+			fname: "main.rs",
+			source: `fn main() {
+    crate::net::fetch();
+}
+`,
+			kind: TagKindRef,
+			tag:  "fetch",
+			want: "net.fetch",
+		},
+		{
+			name:  "C++ nested namespace call",
+			fname: "main.cpp",
+			source: `int main() {
+    net::http::fetch();
+}
+`,
+			kind: TagKindRef,
+			tag:  "fetch",
+			want: "net.http.fetch",
+		},
+		{
+			name:  "C++ nested namespace definition",
+			fname: "http.cpp",
+			source: `void net::http::fetch() {}
+`,
+			kind: TagKindDef,
+			tag:  "fetch",
+			want: "net.http.fetch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), tt.fname)
+			if err := os.WriteFile(fname, []byte(tt.source), 0o644); err != nil {
+				t.Fatalf("Failed to write source: %v", err)
+			}
+
+			r := &RepoMap{}
+			tags, err := r.GetTagsRaw(fname, tt.fname, nil)
+			if err != nil {
+				t.Fatalf("Failed to get tags: %v", err)
+			}
+
+			tg := findTag(tags, tt.kind, tt.tag)
+			if assert.NotNil(t, tg, "Expected %s tag for %s", tt.kind, tt.tag) {
+				assert.Equal(t, tt.want, tg.QualifiedName())
+			}
+		})
+	}
+}
+
+// TestBuildReferenceMapsQualified verifies same-named definitions on
+// different scopes stay distinct, and qualified references only point to the
+// definition they name.
+func TestBuildReferenceMapsQualified(t *testing.T) {
+	r := &RepoMap{}
+
+	allTags := []Tag{
+		{FileName: "server.go", FilePath: "server.go", Line: 3, Name: "Close", Kind: TagKindDef, Scope: []string{"srv", "Server"}},
+		{FileName: "client.go", FilePath: "client.go", Line: 3, Name: "Close", Kind: TagKindDef, Scope: []string{"srv", "Client"}},
+		{FileName: "main.go", FilePath: "main.go", Line: 5, Name: "Close", Kind: TagKindRef, Scope: []string{"Server"}},
+		{FileName: "main.go", FilePath: "main.go", Line: 6, Name: "Close", Kind: TagKindRef, Scope: []string{"conn"}},
+	}
+
+	defines, references, definitions, _ := r.buildReferenceMaps(allTags)
+
+	assert.Contains(t, defines, "srv.Server.Close")
+	assert.Contains(t, defines, "srv.Client.Close")
+	assert.NotContains(t, defines, "Close")
+	assert.Len(t, definitions, 2)
+
+	// Server.Close resolves to the server definition only, while conn.Close
+	// cannot be resolved and points to both.
+	assert.Equal(t, []string{"main.go", "main.go"}, references["srv.Server.Close"])
+	assert.Equal(t, []string{"main.go"}, references["srv.Client.Close"])
+}
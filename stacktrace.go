@@ -0,0 +1,217 @@
+package germ
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Frame is a location referenced by a stack trace or a compiler diagnostic.
+type Frame struct {
+	// Path is the file as written in the trace, or relative to the root once
+	// resolved, see GetFrames
+	Path string
+	// Line is the 1-based line, and Column the 1-based column if known
+	Line   int
+	Column int
+	// Function is the function of a stack frame as written, eg.
+	// main.(*Server).handle, if known
+	Function string
+	// Message is the message of a diagnostic, if any
+	Message string
+}
+
+var (
+	// goFramePattern matches the location line of a Go panic frame, which
+	// follows the function line
+	goFramePattern = regexp.MustCompile(`^\t(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// goFunctionPattern matches the function line of a Go panic frame
+	goFunctionPattern = regexp.MustCompile(`^(\S+)\([^()]*\)$`)
+	// pythonFramePattern matches a Python traceback frame
+	pythonFramePattern = regexp.MustCompile(`^\s*File "(.+)", line (\d+)(?:, in (\S+))?`)
+	// javaFramePattern matches a Java or Kotlin stack frame
+	javaFramePattern = regexp.MustCompile(`^\s*at ([\w$.<>]+)\(([\w$]+\.(?:java|kt)):(\d+)\)`)
+	// jsFramePattern matches a V8 (Node.js, Chrome) stack frame, with or
+	// without a function
+	jsFramePattern = regexp.MustCompile(`^\s*at (?:async )?(?:(\S+?)(?: \[as \S+\])? \()?(?:file://)?(\S+?):(\d+):(\d+)\)?$`)
+	// tscPattern matches a TypeScript compiler diagnostic, eg.
+	// src/app.ts(12,5): error TS2322: ...
+	tscPattern = regexp.MustCompile(`^(\S+?)\((\d+),(\d+)\): (.+)$`)
+	// locationPattern matches file:line[:column] locations anywhere else, eg.
+	// compiler diagnostics, test failures or Rust panics
+	locationPattern = regexp.MustCompile(`([\w./\\@~-]+\.[A-Za-z][A-Za-z0-9]*):(\d+)(?::(\d+))?(?::?\s*(?:-\s*)?(.*))?`)
+)
+
+// ParseStackTrace extracts the frames of Go panics, Python tracebacks,
+// Node.js and Java stack traces, and the locations of compiler diagnostics
+// (go build and vet, tsc, gcc, rustc, ...) found in text, in order.
+func ParseStackTrace(text string) []Frame {
+	var frames []Frame
+	var prev string
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		frames = append(frames, parseTraceLine(line, prev)...)
+		if strings.TrimSpace(line) != "" {
+			prev = line
+		}
+	}
+	return frames
+}
+
+// parseTraceLine returns the frames of a line of text, prev being the
+// previous non-empty line.
+func parseTraceLine(line, prev string) []Frame {
+	atoi := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+
+	if m := goFramePattern.FindStringSubmatch(line); m != nil {
+		f := Frame{Path: m[1], Line: atoi(m[2])}
+		if fm := goFunctionPattern.FindStringSubmatch(prev); fm != nil {
+			f.Function = fm[1]
+		}
+		return []Frame{f}
+	}
+	if m := pythonFramePattern.FindStringSubmatch(line); m != nil {
+		return []Frame{{Path: m[1], Line: atoi(m[2]), Function: m[3]}}
+	}
+	if m := javaFramePattern.FindStringSubmatch(line); m != nil {
+		// The file of a Java frame lives in the directory of its package
+		p := m[2]
+		if parts := strings.Split(m[1], "."); len(parts) > 2 {
+			p = strings.Join(parts[:len(parts)-2], "/") + "/" + p
+		}
+		return []Frame{{Path: p, Line: atoi(m[3]), Function: m[1]}}
+	}
+	if m := jsFramePattern.FindStringSubmatch(line); m != nil {
+		return []Frame{{Path: m[2], Line: atoi(m[3]), Column: atoi(m[4]), Function: m[1]}}
+	}
+	if m := tscPattern.FindStringSubmatch(line); m != nil {
+		return []Frame{{Path: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Message: m[4]}}
+	}
+
+	var frames []Frame
+	for _, m := range locationPattern.FindAllStringSubmatch(line, -1) {
+		frames = append(frames, Frame{Path: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Message: strings.TrimSpace(m[4])})
+	}
+	return frames
+}
+
+// FunctionName returns the bare name of the frame's function, eg. handle for
+// main.(*Server).handle, or "" when unknown or anonymous.
+func (f Frame) FunctionName() string {
+	name := f.Function
+	if i := strings.LastIndexAny(name, ".:"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Trim(name, "()*")
+	if name == "" || strings.ContainsAny(name, "<>") {
+		return ""
+	}
+	return name
+}
+
+// GetFrames parses the stack traces and diagnostics in text and keeps the
+// frames located in fnames, with their path relative to the root. Paths
+// match when absolute under the root, or by their longest suffix shared with
+// a single file, so traces from other machines or working directories still
+// resolve.
+func (r *RepoMap) GetFrames(text string, fnames []string) []Frame {
+	files := make([]string, 0, len(fnames))
+	for _, f := range fnames {
+		files = append(files, filepath.ToSlash(r.GetRelFname(f)))
+	}
+	sort.Strings(files)
+
+	var frames []Frame
+	for _, f := range ParseStackTrace(text) {
+		if rel, ok := r.resolveFramePath(f.Path, files); ok {
+			f.Path = rel
+			// Lines past the end of the file, eg. from another revision,
+			// have nothing to show
+			if f.Line > r.lineCount(filepath.Join(r.root, filepath.FromSlash(rel))) {
+				f.Line = 0
+			}
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// lineCount returns the number of lines of a file, 0 when unreadable.
+func (r *RepoMap) lineCount(fname string) int {
+	lines := r.sourceLines(fname)
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		return n - 1
+	}
+	return len(lines)
+}
+
+// resolveFramePath maps a path of a trace to one of the files, relative to
+// the root.
+func (r *RepoMap) resolveFramePath(p string, files []string) (string, bool) {
+	p = filepath.ToSlash(p)
+	if filepath.IsAbs(filepath.FromSlash(p)) {
+		if rel, err := filepath.Rel(r.root, filepath.FromSlash(p)); err == nil && !strings.HasPrefix(rel, "..") {
+			p = filepath.ToSlash(rel)
+		}
+	}
+	p = strings.TrimPrefix(path.Clean(p), "./")
+
+	var best []string
+	bestLen := 0
+	for _, f := range files {
+		var n int
+		switch {
+		case p == f:
+			return f, true
+		case strings.HasSuffix(p, "/"+f):
+			// An absolute path from another checkout
+			n = len(f)
+		case strings.HasSuffix(f, "/"+p):
+			// A path relative to a subdirectory
+			n = len(p)
+		default:
+			continue
+		}
+		if n > bestLen {
+			best, bestLen = []string{f}, n
+		} else if n == bestLen {
+			best = append(best, f)
+		}
+	}
+	if len(best) != 1 {
+		return "", false
+	}
+	return best[0], true
+}
+
+// addFrames records the files, functions and lines of frames as mentions.
+// Functions are only recorded when they are known identifiers.
+func (m Mentions) addFrames(frames []Frame, known map[string]struct{}) {
+	for _, f := range frames {
+		m.addFile(f.Path, mentionExact)
+		if name := f.FunctionName(); name != "" {
+			if _, ok := known[name]; ok {
+				m.addIdent(name, mentionExact)
+			}
+		}
+		if f.Line > 0 {
+			m.Lines[f.Path] = appendUniqueInt(m.Lines[f.Path], f.Line-1)
+		}
+	}
+}
+
+// appendUniqueInt appends v to s unless already present.
+func appendUniqueInt(s []int, v int) []int {
+	for _, x := range s {
+		if x == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
@@ -0,0 +1,161 @@
+package germ
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseStackTrace verifies frames are extracted from common stack trace
+// and diagnostic formats.
+func TestParseStackTrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace string
+		want  []Frame
+	}{
+		{
+			name: "Go panic",
+			trace: `panic: runtime error: invalid memory address or nil pointer dereference
+goroutine 1 [running]:
+example.com/app/srv.(*Server).dispatch(0xc000010000, {0x0, 0x0})
+	/home/ci/app/srv/server.go:42 +0x1d
+main.main()
+	/home/ci/app/main.go:10 +0x25
+`,
+			want: []Frame{
+				{Path: "/home/ci/app/srv/server.go", Line: 42, Function: "example.com/app/srv.(*Server).dispatch"},
+				{Path: "/home/ci/app/main.go", Line: 10, Function: "main.main"},
+			},
+		},
+		{
+			name: "Python traceback",
+			trace: `Traceback (most recent call last):
+  File "/srv/app/billing/invoice.py", line 27, in compute_total
+    return sum(lines)
+TypeError: unsupported operand type(s)
+`,
+			want: []Frame{
+				{Path: "/srv/app/billing/invoice.py", Line: 27, Function: "compute_total"},
+			},
+		},
+		{
+			name: "Node.js stack",
+			trace: `TypeError: Cannot read properties of undefined
+    at Store.hydrateState (/app/src/store.js:88:17)
+    at async Promise.all (index 0)
+    at file:///app/src/index.js:5:3
+`,
+			want: []Frame{
+				{Path: "/app/src/store.js", Line: 88, Column: 17, Function: "Store.hydrateState"},
+				{Path: "/app/src/index.js", Line: 5, Column: 3},
+			},
+		},
+		{
+			name: "Java stack",
+			trace: `java.lang.IllegalStateException: closed
+	at com.acme.ledger.Account.settle(Account.java:57)
+`,
+			want: []Frame{
+				{Path: "com/acme/ledger/Account.java", Line: 57, Function: "com.acme.ledger.Account.settle"},
+			},
+		},
+		{
+			name:  "go build",
+			trace: "# example.com/app/srv\n./srv/server.go:12:5: undefined: dispatchQueue\n",
+			want: []Frame{
+				{Path: "./srv/server.go", Line: 12, Column: 5, Message: "undefined: dispatchQueue"},
+			},
+		},
+		{
+			name:  "tsc",
+			trace: "src/store.ts(14,3): error TS2322: Type 'string' is not assignable to type 'number'.\n",
+			want: []Frame{
+				{Path: "src/store.ts", Line: 14, Column: 3, Message: "error TS2322: Type 'string' is not assignable to type 'number'."},
+			},
+		},
+		{
+			name:  "Rust panic",
+			trace: "thread 'main' panicked at src/engine.rs:31:9:\ncalled `Option::unwrap()` on a `None` value\n",
+			want: []Frame{
+				{Path: "src/engine.rs", Line: 31, Column: 9},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseStackTrace(tt.trace))
+		})
+	}
+}
+
+func TestFrameFunctionName(t *testing.T) {
+	assert.Equal(t, "dispatch", Frame{Function: "example.com/app/srv.(*Server).dispatch"}.FunctionName())
+	assert.Equal(t, "hydrateState", Frame{Function: "Store.hydrateState"}.FunctionName())
+	assert.Equal(t, "compute_total", Frame{Function: "compute_total"}.FunctionName())
+	assert.Equal(t, "", Frame{Function: "Object.<anonymous>"}.FunctionName())
+	assert.Equal(t, "", Frame{}.FunctionName())
+}
+
+// TestGetFrames verifies frames resolve to repository files from absolute
+// paths, other checkouts and subdirectories, and others are dropped.
+func TestGetFrames(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"srv/server.go":   "package srv\n" + strings.Repeat("\n", 50),
+		"main.go":         "package main\n" + strings.Repeat("\n", 10),
+		"web/src/app.ts":  "export {}\n\n\n",
+		"a/util/conv.go":  "package util\n",
+		"b/util/conv.go":  "package util\n",
+		"tools/report.py": "\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	trace := filepath.Join(root, "main.go") + ":7: boom\n" +
+		"\t/home/ci/app/srv/server.go:42 +0x1d\n" +
+		"src/app.ts(3,1): error TS1005: ';' expected.\n" +
+		"util/conv.go:9: ambiguous\n" +
+		"\t/usr/local/go/src/runtime/panic.go:770 +0x132\n" +
+		"web/src/app.ts(4,1): error TS1128: Declaration expected.\n"
+
+	// Lines past the end of a file are dropped
+	assert.Equal(t, []Frame{
+		{Path: "main.go", Line: 7, Message: "boom"},
+		{Path: "srv/server.go", Line: 42},
+		{Path: "web/src/app.ts", Line: 3, Column: 1, Message: "error TS1005: ';' expected."},
+		{Path: "web/src/app.ts", Column: 1, Message: "error TS1128: Declaration expected."},
+	}, r.GetFrames(trace, fnames))
+}
+
+// TestGetMentionsFromStackTrace verifies pasted traces set the mentioned
+// files and functions, and the lines shown in the map.
+func TestGetMentionsFromStackTrace(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"srv/server.go": "package srv\n\ntype Server struct{}\n\nfunc (s *Server) dispatch() {\n\tpanic(\"boom\")\n}\n",
+		"srv/queue.go":  "package srv\n\nfunc drainQueue() {}\n",
+	})
+
+	r := NewRepoMap(root, nil)
+	m := r.GetMentions([]string{`it crashes:
+goroutine 1 [running]:
+example.com/app/srv.(*Server).dispatch(...)
+	/home/ci/app/srv/server.go:6 +0x1d
+`}, fnames)
+
+	assert.Equal(t, map[string]float64{"srv/server.go": mentionExact}, m.Files)
+	// Server is also written in the trace
+	assert.Equal(t, map[string]float64{"Server": mentionExact, "dispatch": mentionExact}, m.Idents)
+	assert.Equal(t, map[string][]int{"srv/server.go": {5}}, m.Lines)
+
+	r.linesOfInterest = m.Lines
+	assert.Equal(t, []Tag{{
+		FileName: "srv/server.go",
+		FilePath: filepath.Join(root, "srv/server.go"),
+		Line:     5,
+		EndLine:  5,
+	}}, r.linesOfInterestTags())
+}
@@ -0,0 +1,194 @@
+package germ
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// symbolEdge is a weighted edge between two symbol graph nodes.
+type symbolEdge struct {
+	src, dst SymbolKey
+}
+
+// symbolReferences returns the definitions of each symbol, the weighted
+// reference counts between symbol nodes, file nodes having an empty symbol,
+// and the definitions referenced. Each reference links the innermost
+// definition enclosing it, or its file, to the definitions it resolves to.
+func (r *RepoMap) symbolReferences(cfg RankingConfig, allTags []Tag, mentionedIdents map[string]bool) (
+	definitions map[SymbolKey][]Tag,
+	counts map[symbolEdge]float64,
+	referenced map[SymbolKey]struct{},
+) {
+	defines, qualifiedByName := r.indexDefinitions(allTags)
+
+	// Definitions per file, to attribute each reference to its enclosing one
+	definitions = make(map[SymbolKey][]Tag)
+	byFile := make(map[string][]Tag)
+	for _, t := range allTags {
+		if t.Kind != TagKindDef {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
+		k := SymbolKey{FileName: rel, Symbol: t.QualifiedName()}
+		definitions[k] = append(definitions[k], t)
+		byFile[rel] = append(byFile[rel], t)
+	}
+
+	counts = make(map[symbolEdge]float64)
+	referenced = make(map[SymbolKey]struct{})
+	for _, t := range allTags {
+		if t.Kind != TagKindRef {
+			continue
+		}
+		rel := r.GetRelFname(t.FilePath)
+
+		src := SymbolKey{FileName: rel}
+		if enclosing, ok := enclosingDefinition(byFile[rel], t.Line); ok {
+			src.Symbol = enclosing.QualifiedName()
+		}
+
+		symbols := resolveReference(t, qualifiedByName[t.Name])
+		for _, symbol := range narrowByImports(r.deps, rel, symbols, defines) {
+			for _, defFile := range r.deps.Targets(rel, defines[symbol]) {
+				dst := SymbolKey{FileName: defFile, Symbol: symbol}
+				if dst == src {
+					continue
+				}
+				counts[symbolEdge{src: src, dst: dst}] += cfg.identWeight(symbol, definitionInfo(definitions[dst]), mentionedIdents)
+				referenced[dst] = struct{}{}
+			}
+		}
+	}
+	return definitions, counts, referenced
+}
+
+// getRankedTagsBySymbolRank ranks definitions rather than files. Each
+// definition is a graph node, and each reference links the innermost
+// definition enclosing it, or its file when there is none, to the definitions
+// it resolves to. Files link to their definitions, and to each other through
+// non-identifier edges such as imports, so file level signals still flow to
+// symbols.
+func (r *RepoMap) getRankedTagsBySymbolRank(allTags []Tag, mentionedFnames, mentionedIdents map[string]bool) []Tag {
+	cfg := r.ranking()
+	definitions, counts, referenced := r.symbolReferences(cfg, allTags, mentionedIdents)
+
+	// Without any reference, rank every definition
+	if len(referenced) == 0 {
+		for k := range definitions {
+			referenced[k] = struct{}{}
+		}
+	}
+
+	g := simple.NewWeightedDirectedGraph(0, 0)
+	nodes := make(map[SymbolKey]graph.Node)
+	node := func(k SymbolKey) graph.Node {
+		if n, ok := nodes[k]; ok {
+			return n
+		}
+		n := g.NewNode()
+		g.AddNode(n)
+		nodes[k] = n
+		return n
+	}
+	// Edges between the same nodes are summed, and recorded by symbol or kind
+	// for Explain
+	traced := make(edgeTrace)
+	addEdge := func(src, dst SymbolKey, via string, w float64) {
+		if src == dst || w <= 0 {
+			return
+		}
+		traced.add(src, dst, via, w)
+		from, to := node(src), node(dst)
+		if e := g.WeightedEdge(from.ID(), to.ID()); e != nil {
+			w += e.Weight()
+		}
+		g.SetWeightedEdge(g.NewWeightedEdge(from, to, w))
+	}
+
+	// Create nodes in a stable order so ties rank deterministically
+	keys := make([]SymbolKey, 0, len(definitions))
+	for k := range definitions {
+		keys = append(keys, k)
+	}
+	sortSymbolKeys(keys)
+	for _, k := range keys {
+		node(SymbolKey{FileName: k.FileName})
+		node(k)
+	}
+
+	// References, weighted by their count and the history of the defining
+	// file like file edges
+	for e, c := range counts {
+		addEdge(e.src, e.dst, e.dst.Symbol, cfg.ReferenceWeight(c)*r.historyBoost(e.dst.FileName))
+	}
+
+	// Each file spreads a unit weight across the definitions it contains
+	perFile := make(map[string]int)
+	for _, k := range keys {
+		perFile[k.FileName]++
+	}
+	for _, k := range keys {
+		addEdge(SymbolKey{FileName: k.FileName}, k, "contains", 1/float64(perFile[k.FileName]))
+	}
+
+	// Non-identifier edges between files, eg. imports, or to the definition
+	// they rank, eg. the subtype of a relation
+	for _, e := range r.fileEdges {
+		dst := SymbolKey{FileName: e.dst, Symbol: e.symbol}
+		if _, ok := definitions[dst]; !ok {
+			dst.Symbol = ""
+		} else if e.weight > 0 {
+			referenced[dst] = struct{}{}
+		}
+		addEdge(SymbolKey{FileName: e.src}, dst, e.kind, e.weight)
+	}
+
+	// Personalize towards the files mentioned in the chat, and their definitions
+	personal := make(map[int64]float64)
+	for k, n := range nodes {
+		if mentionedFnames[k.FileName] {
+			personal[n.ID()] = cfg.ChatFileWeight
+		} else {
+			personal[n.ID()] = 1
+		}
+		personal[n.ID()] *= r.proximityBoost(k.FileName)
+	}
+
+	pr := r.ranker().Rank(g, personal)
+
+	var ranked []DefRank
+	for k := range referenced {
+		n, ok := nodes[k]
+		if !ok {
+			continue
+		}
+		ranked = append(ranked, DefRank{fname: k.FileName, symbol: k.Symbol, rank: pr[n.ID()]})
+	}
+	ranked, relevance := r.blendQuery(ranked, allTags)
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].rank != ranked[j].rank {
+			return ranked[i].rank > ranked[j].rank
+		}
+		if ranked[i].fname != ranked[j].fname {
+			return ranked[i].fname < ranked[j].fname
+		}
+		return ranked[i].symbol < ranked[j].symbol
+	})
+
+	// Record the ranking for Explain
+	nodeScores := make(map[SymbolKey]float64, len(nodes))
+	personalization := make(map[SymbolKey]float64, len(nodes))
+	for k, n := range nodes {
+		nodeScores[k] = pr[n.ID()]
+		personalization[k] = personal[n.ID()]
+	}
+	r.traceRanking(allTags, mentionedFnames, mentionedIdents, nodeScores, personalization, traced, relevance, ranked)
+
+	var rankedTags []Tag
+	for _, dr := range ranked {
+		rankedTags = append(rankedTags, definitions[SymbolKey{FileName: dr.fname, Symbol: dr.symbol}]...)
+	}
+	return rankedTags
+}
@@ -0,0 +1,53 @@
+package germ
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetRankedTagsBySymbolRank verifies symbol ranking favours what central
+// functions call over cold code that happens to live in a central file.
+func TestGetRankedTagsBySymbolRank(t *testing.T) {
+	root := t.TempDir()
+	fnames := writeTestFiles(t, root, map[string]string{
+		"a.go": "package app\n\nfunc alpha() { hotPath() }\n",
+		"b.go": "package app\n\nfunc bravo() { hotPath() }\n",
+		"c.go": "package app\n\nfunc charlie() { hotPath() }\n",
+		"big.go": `package app
+
+func hotPath() {
+	leafHelper()
+}
+
+func coldCaller() {
+	coldTarget()
+}
+
+func coldTarget() {}
+`,
+		"leaf.go": "package app\n\nfunc leafHelper() {}\n",
+	})
+
+	position := func(tags []Tag, name string) int {
+		for i, tg := range tags {
+			if tg.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	r := &RepoMap{root: root}
+	allTags := r.getTagsFromFiles(fnames, commonWords)
+
+	byFile := r.getRankedTagsByPageRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Less(t, position(byFile, "coldTarget"), position(byFile, "leafHelper"))
+
+	bySymbol := r.getRankedTagsBySymbolRank(allTags, map[string]bool{}, map[string]bool{})
+	assert.Less(t, position(bySymbol, "hotPath"), position(bySymbol, "coldTarget"))
+	assert.Less(t, position(bySymbol, "leafHelper"), position(bySymbol, "coldTarget"))
+
+	// Only referenced definitions are ranked
+	assert.Equal(t, -1, position(bySymbol, "alpha"))
+}
@@ -0,0 +1,301 @@
+package germ
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const (
+	// VisibilityPublic is exported API: Go capitalized names, public or
+	// exported declarations, class members without a restricting modifier
+	VisibilityPublic = "public"
+	// VisibilityProtected is visible to subclasses only
+	VisibilityProtected = "protected"
+	// VisibilityPackage is visible within the package, assembly or crate
+	// only: Go unexported names, Java package-private, C# internal, Rust
+	// pub(crate)
+	VisibilityPackage = "package"
+	// VisibilityPrivate is visible within its file, module or class only
+	VisibilityPrivate = "private"
+)
+
+// visibilityOrder ranks visibilities from the most to the least visible. An
+// unknown visibility sits between package and private.
+var visibilityOrder = map[string]int{
+	VisibilityPublic:    4,
+	VisibilityProtected: 3,
+	VisibilityPackage:   2,
+	"":                  1,
+	VisibilityPrivate:   0,
+}
+
+// declaratorKinds are the CST nodes between a definition's name and its
+// declaration, eg. a C function_declarator or a Java variable_declarator.
+var declaratorKinds = map[string]struct{}{
+	"variable_declarator":  {},
+	"variable_declaration": {},
+	"function_declarator":  {},
+	"pointer_declarator":   {},
+	"reference_declarator": {},
+	"init_declarator":      {},
+	"array_declarator":     {},
+	"qualified_identifier": {},
+}
+
+// tagVisibility returns the visibility of the definition named by node, or ""
+// when the language has no visibility rules known to germ.
+func tagVisibility(node *sitter.Node, sourceCode []byte, langID string) string {
+	name := node.Utf8Text(sourceCode)
+
+	decl := node.Parent()
+	for decl != nil {
+		if _, ok := declaratorKinds[decl.Kind()]; !ok {
+			break
+		}
+		decl = decl.Parent()
+	}
+	if decl == nil {
+		return ""
+	}
+
+	switch langID {
+	case "go":
+		r, _ := utf8.DecodeRuneInString(name)
+		if unicode.IsUpper(r) {
+			return VisibilityPublic
+		}
+		return VisibilityPackage
+	case "python":
+		return underscoreVisibility(name)
+	case "java":
+		return javaVisibility(decl, sourceCode)
+	case "c_sharp":
+		return csharpVisibility(decl, sourceCode)
+	case "javascript", "typescript", "tsx":
+		return jsVisibility(node, decl, sourceCode)
+	case "rust":
+		return rustVisibility(decl, sourceCode)
+	case "c", "cpp":
+		return cVisibility(decl, sourceCode)
+	}
+	return ""
+}
+
+// underscoreVisibility applies the Python convention: a leading underscore
+// marks a private name, except for dunder names such as __init__.
+func underscoreVisibility(name string) string {
+	if strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__") {
+		return VisibilityPublic
+	}
+	if strings.HasPrefix(name, "_") {
+		return VisibilityPrivate
+	}
+	return VisibilityPublic
+}
+
+// modifierVisibility returns the visibility keyword among the words of a
+// modifiers node, if any.
+func modifierVisibility(text string) string {
+	for _, word := range strings.Fields(text) {
+		switch word {
+		case "public":
+			return VisibilityPublic
+		case "protected":
+			return VisibilityProtected
+		case "private":
+			return VisibilityPrivate
+		case "internal":
+			return VisibilityPackage
+		}
+	}
+	return ""
+}
+
+// javaVisibility reads the modifiers of a Java declaration. Interface members
+// are implicitly public, other declarations package-private.
+func javaVisibility(decl *sitter.Node, sourceCode []byte) string {
+	for i := uint(0); i < decl.NamedChildCount(); i++ {
+		if child := decl.NamedChild(i); child.Kind() == "modifiers" {
+			if v := modifierVisibility(child.Utf8Text(sourceCode)); v != "" {
+				return v
+			}
+		}
+	}
+	if p := decl.Parent(); p != nil && p.Kind() == "interface_body" {
+		return VisibilityPublic
+	}
+	return VisibilityPackage
+}
+
+// csharpVisibility reads the modifiers of a C# declaration. Interface members
+// are implicitly public, top-level types internal and members private.
+func csharpVisibility(decl *sitter.Node, sourceCode []byte) string {
+	var words []string
+	for i := uint(0); i < decl.NamedChildCount(); i++ {
+		if child := decl.NamedChild(i); child.Kind() == "modifier" {
+			words = append(words, child.Utf8Text(sourceCode))
+		}
+	}
+	if v := modifierVisibility(strings.Join(words, " ")); v != "" {
+		return v
+	}
+
+	p := decl.Parent()
+	if p != nil && p.Kind() == "declaration_list" {
+		if owner := p.Parent(); owner != nil && owner.Kind() == "interface_declaration" {
+			return VisibilityPublic
+		}
+		if owner := p.Parent(); owner != nil && owner.Kind() != "namespace_declaration" {
+			return VisibilityPrivate
+		}
+	}
+	return VisibilityPackage
+}
+
+// jsVisibility returns the visibility of a JavaScript or TypeScript
+// definition. Class and interface members are public unless marked private,
+// protected or #private. Module level declarations are public when exported,
+// and private otherwise in files using ES module exports.
+func jsVisibility(node, decl *sitter.Node, sourceCode []byte) string {
+	if node.Kind() == "private_property_identifier" {
+		return VisibilityPrivate
+	}
+	for i := uint(0); i < decl.NamedChildCount(); i++ {
+		if child := decl.NamedChild(i); child.Kind() == "accessibility_modifier" {
+			return modifierVisibility(child.Utf8Text(sourceCode))
+		}
+	}
+
+	for p := decl; p != nil; p = p.Parent() {
+		switch p.Kind() {
+		case "export_statement":
+			return VisibilityPublic
+		case "class_body", "interface_body", "object_type":
+			return VisibilityPublic
+		case "program":
+			if hasChildKind(p, "export_statement") {
+				return VisibilityPrivate
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// rustVisibility reads the visibility modifier of a Rust item. Trait items,
+// trait impl items and enum variants share the visibility of their trait or
+// enum, and are considered public.
+func rustVisibility(decl *sitter.Node, sourceCode []byte) string {
+	for i := uint(0); i < decl.NamedChildCount(); i++ {
+		child := decl.NamedChild(i)
+		if child.Kind() != "visibility_modifier" {
+			continue
+		}
+		switch strings.ReplaceAll(child.Utf8Text(sourceCode), " ", "") {
+		case "pub":
+			return VisibilityPublic
+		case "pub(self)":
+			return VisibilityPrivate
+		default:
+			return VisibilityPackage
+		}
+	}
+
+	if p := decl.Parent(); p != nil {
+		switch p.Kind() {
+		case "enum_variant_list":
+			return VisibilityPublic
+		case "declaration_list":
+			if owner := p.Parent(); owner != nil {
+				if owner.Kind() == "trait_item" || owner.ChildByFieldName("trait") != nil {
+					return VisibilityPublic
+				}
+			}
+		}
+	}
+	return VisibilityPrivate
+}
+
+// cVisibility returns the visibility of a C or C++ definition: static file
+// scope declarations are private, class members follow the preceding access
+// specifier, private by default in classes and public in structs and unions.
+func cVisibility(decl *sitter.Node, sourceCode []byte) string {
+	if p := decl.Parent(); p != nil && p.Kind() == "field_declaration_list" {
+		v := VisibilityPublic
+		if owner := p.Parent(); owner != nil && owner.Kind() == "class_specifier" {
+			v = VisibilityPrivate
+		}
+		for s := decl.PrevNamedSibling(); s != nil; s = s.PrevNamedSibling() {
+			if s.Kind() == "access_specifier" {
+				return modifierVisibility(s.Utf8Text(sourceCode))
+			}
+		}
+		return v
+	}
+
+	for i := uint(0); i < decl.NamedChildCount(); i++ {
+		child := decl.NamedChild(i)
+		if child.Kind() == "storage_class_specifier" && child.Utf8Text(sourceCode) == "static" {
+			return VisibilityPrivate
+		}
+	}
+	return VisibilityPublic
+}
+
+// hasChildKind reports whether n has a named child of the given kind.
+func hasChildKind(n *sitter.Node, kind string) bool {
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		if n.NamedChild(i).Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// mostVisible returns the most visible visibility among definitions.
+func mostVisible(defs []Tag) string {
+	if len(defs) == 0 {
+		return ""
+	}
+	v := defs[0].Visibility
+	for _, d := range defs[1:] {
+		if visibilityOrder[d.Visibility] > visibilityOrder[v] {
+			v = d.Visibility
+		}
+	}
+	return v
+}
+
+// symbolInfo is the bare name of a qualified symbol, as tagged, and the most
+// visible visibility of its definitions.
+type symbolInfo struct {
+	name       string
+	visibility string
+}
+
+// definitionInfo returns the symbolInfo of the definitions of a symbol.
+func definitionInfo(defs []Tag) symbolInfo {
+	if len(defs) == 0 {
+		return symbolInfo{}
+	}
+	return symbolInfo{name: defs[0].Name, visibility: mostVisible(defs)}
+}
+
+// symbolInfos returns the symbolInfo of each qualified symbol defined by the
+// tags.
+func symbolInfos(allTags []Tag) map[string]symbolInfo {
+	defs := make(map[string][]Tag)
+	for _, t := range allTags {
+		if t.Kind == TagKindDef {
+			defs[t.QualifiedName()] = append(defs[t.QualifiedName()], t)
+		}
+	}
+	infos := make(map[string]symbolInfo, len(defs))
+	for symbol, d := range defs {
+		infos[symbol] = definitionInfo(d)
+	}
+	return infos
+}
@@ -0,0 +1,193 @@
+package germ
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTagVisibility verifies definitions record their visibility per language.
+func TestTagVisibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		fname  string
+		source string
+		want   map[string]string
+	}{
+		{
+			name:  "Go",
+			fname: "srv/server.go",
+			source: `package srv
+
+type Server struct{}
+
+func (s *Server) Shutdown() {}
+
+func drainQueue() {}
+`,
+			want: map[string]string{
+				"Server":     VisibilityPublic,
+				"Shutdown":   VisibilityPublic,
+				"drainQueue": VisibilityPackage,
+			},
+		},
+		{
+			name:  "Python",
+			fname: "pkg/service.py",
+			source: `class Service:
+    def __init__(self):
+        pass
+
+    def _reload(self):
+        pass
+
+def start_service():
+    pass
+`,
+			want: map[string]string{
+				"Service":       VisibilityPublic,
+				"__init__":      VisibilityPublic,
+				"_reload":       VisibilityPrivate,
+				"start_service": VisibilityPublic,
+			},
+		},
+		{
+			name:  "Java",
+			fname: "src/Account.java",
+			source: `public class Account {
+    private void audit() {}
+    protected void settle() {}
+    void reconcile() {}
+    public void deposit() {}
+}
+
+interface Ledger {
+    void record();
+}
+`,
+			want: map[string]string{
+				"Account":   VisibilityPublic,
+				"audit":     VisibilityPrivate,
+				"settle":    VisibilityProtected,
+				"reconcile": VisibilityPackage,
+				"deposit":   VisibilityPublic,
+				"Ledger":    VisibilityPackage,
+				"record":    VisibilityPublic,
+			},
+		},
+		{
+			name:  "TypeScript",
+			fname: "src/store.ts",
+			source: `export function createStore() {}
+
+function hydrateState() {}
+
+export class Store {
+    private evictEntry() {}
+    protected notifyAll() {}
+    dispatch() {}
+}
+`,
+			want: map[string]string{
+				"createStore":  VisibilityPublic,
+				"hydrateState": VisibilityPrivate,
+				"Store":        VisibilityPublic,
+				"evictEntry":   VisibilityPrivate,
+				"notifyAll":    VisibilityProtected,
+				"dispatch":     VisibilityPublic,
+			},
+		},
+		{
+			name:  "JavaScript without ES exports",
+			fname: "lib/queue.js",
+			source: `function enqueueJob() {}
+
+class Worker {
+    #pollQueue() {}
+    process() {}
+}
+`,
+			want: map[string]string{
+				"enqueueJob": "",
+				"Worker":     "",
+				"#pollQueue": VisibilityPrivate,
+				"process":    VisibilityPublic,
+			},
+		},
+		{
+			name:  "Rust",
+			fname: "src/engine.rs",
+			source: `pub fn launch() {}
+
+fn ignite() {}
+
+pub(crate) struct Engine;
+
+impl Engine {
+    pub fn throttle(&self) {}
+    fn vent(&self) {}
+}
+`,
+			want: map[string]string{
+				"launch":   VisibilityPublic,
+				"ignite":   VisibilityPrivate,
+				"Engine":   VisibilityPackage,
+				"throttle": VisibilityPublic,
+				"vent":     VisibilityPrivate,
+			},
+		},
+		{
+			name:  "C",
+			fname: "src/buffer.c",
+			source: `static int grow_buffer(void) { return 0; }
+
+int flush_buffer(void) { return 0; }
+`,
+			want: map[string]string{
+				"grow_buffer":  VisibilityPrivate,
+				"flush_buffer": VisibilityPublic,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFiles(t, root, map[string]string{tt.fname: tt.source})
+
+			r := NewRepoMap(root, nil)
+			tags, err := r.GetTagsRaw(filepath.Join(root, tt.fname), tt.fname, nil)
+			assert.NoError(t, err)
+
+			for name, want := range tt.want {
+				tag := findTag(tags, TagKindDef, name)
+				if !assert.NotNil(t, tag, name) {
+					continue
+				}
+				assert.Equal(t, want, tag.Visibility, name)
+			}
+		})
+	}
+}
+
+// TestVisibilityWeight verifies references to exported definitions weigh more
+// than references to restricted or private ones.
+func TestVisibilityWeight(t *testing.T) {
+	cfg := DefaultRankingConfig()
+
+	assert.Equal(t, 1.0, cfg.identWeight("Publish", symbolInfo{name: "Publish", visibility: VisibilityPublic}, nil))
+	assert.Equal(t, 0.5, cfg.identWeight("settle", symbolInfo{name: "settle", visibility: VisibilityProtected}, nil))
+	assert.Equal(t, 0.5, cfg.identWeight("reconcile", symbolInfo{name: "reconcile", visibility: VisibilityPackage}, nil))
+	assert.Equal(t, 0.1, cfg.identWeight("drainQueue", symbolInfo{name: "drainQueue", visibility: VisibilityPrivate}, nil))
+	assert.Equal(t, 1.0, cfg.identWeight("enqueueJob", symbolInfo{name: "enqueueJob"}, nil))
+
+	cfg.ExportedWeight = 3
+	assert.Equal(t, 3.0, cfg.identWeight("Publish", symbolInfo{name: "Publish", visibility: VisibilityPublic}, nil))
+
+	// The most visible definition of a symbol wins
+	assert.Equal(t, VisibilityPublic, mostVisible([]Tag{
+		{Visibility: VisibilityPrivate},
+		{Visibility: VisibilityPublic},
+	}))
+}